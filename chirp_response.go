@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// newChirpResponse maps a database chirp onto the API shape. Chirps hidden
+// by a moderator keep their place in threads but lose their body.
+// Aggregate fields such as ReplyCount are filled in by chirpResponses.
+func newChirpResponse(c database.Chirp) chirpResponse {
+	resp := chirpResponse{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body:      c.Body,
+		UserId:    c.UserID,
+		Deleted:   c.DeletedAt.Valid,
+		Hidden:    c.HiddenAt.Valid,
+	}
+	if resp.Hidden {
+		resp.Body = ""
+	}
+	if c.ParentChirpID.Valid {
+		parentID := c.ParentChirpID.UUID
+		resp.ParentChirpID = &parentID
+	}
+	return resp
+}
+
+// chirpResponses maps a slice of chirps and fills in their reply and like
+// counts with one aggregate query each instead of one lookup per chirp.
+// viewerID is the authenticated caller, or uuid.Nil for anonymous requests,
+// and decides LikedByMe.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp, viewerID uuid.UUID) ([]chirpResponse, error) {
+	resp := make([]chirpResponse, 0, len(chirps))
+	if len(chirps) == 0 {
+		return resp, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+
+	counts, err := cfg.queries.CountRepliesForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	replyCounts := make(map[uuid.UUID]int64, len(counts))
+	for _, c := range counts {
+		replyCounts[c.ParentChirpID.UUID] = c.ReplyCount
+	}
+
+	likes, err := cfg.queries.CountLikesForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	likeCounts := make(map[uuid.UUID]int64, len(likes))
+	for _, l := range likes {
+		likeCounts[l.ChirpID] = l.LikeCount
+	}
+
+	likedByMe := map[uuid.UUID]bool{}
+	if viewerID != uuid.Nil {
+		liked, err := cfg.queries.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+			UserID:   viewerID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range liked {
+			likedByMe[id] = true
+		}
+	}
+
+	for _, c := range chirps {
+		r := newChirpResponse(c)
+		r.ReplyCount = replyCounts[c.ID]
+		r.LikeCount = likeCounts[c.ID]
+		r.LikedByMe = likedByMe[c.ID]
+		resp = append(resp, r)
+	}
+	return resp, nil
+}
+
+// respondWithChirpPage writes one page of a keyset-paginated chirp listing.
+// chirps must have been fetched with a page size of limit+1; the extra row
+// only tells us that a next page exists and is not returned.
+func (cfg *apiConfig) respondWithChirpPage(w http.ResponseWriter, r *http.Request, chirps []database.Chirp, limit int32, viewerID uuid.UUID) {
+	var page chirpPage
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	var err error
+	page.Chirps, err = cfg.chirpResponses(r.Context(), chirps, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirps", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
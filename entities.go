@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxTagLength = 50
+
+var (
+	// a tag or mention only starts at the beginning of the body or after a
+	// character that cannot be part of a word, so "a#b" is not a tag.
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#@])#([\p{L}\p{N}_]+)`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#@])@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+)
+
+// extractHashtags returns the distinct, lower-cased #tags in a chirp body.
+func extractHashtags(body string) []string {
+	seen := map[string]struct{}{}
+	tags := []string{}
+	for _, m := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(m[1])
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// extractMentions returns the distinct email addresses mentioned as
+// @someone@example.com in a chirp body.
+func extractMentions(body string) []string {
+	seen := map[string]struct{}{}
+	emails := []string{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.TrimRight(m[1], ".")
+		if _, ok := seen[email]; ok {
+			continue
+		}
+		seen[email] = struct{}{}
+		emails = append(emails, email)
+	}
+	return emails
+}
+
+// saveChirpEntities makes the stored tags and mentions of a chirp match the
+// ones found in body. Ones that are still there are left alone, so an edit
+// doesn't reset when a tag was first used and push it back into trending.
+// q should be bound to the transaction that writes the chirp itself.
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	tags := extractHashtags(body)
+	emails := extractMentions(body)
+	err := q.DeleteStaleChirpTags(ctx, database.DeleteStaleChirpTagsParams{
+		ChirpID: chirpID,
+		Keep:    tags,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.DeleteStaleChirpMentions(ctx, database.DeleteStaleChirpMentionsParams{
+		ChirpID:    chirpID,
+		KeepEmails: emails,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(tags) > 0 {
+		err := q.AddChirpTags(ctx, database.AddChirpTagsParams{
+			ChirpID: chirpID,
+			Tags:    tags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(emails) > 0 {
+		err := q.AddChirpMentions(ctx, database.AddChirpMentionsParams{
+			ChirpID: chirpID,
+			Emails:  emails,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
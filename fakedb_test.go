@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// fakeDB stands in for Postgres in handler tests. Queries are routed by the
+// name in their sqlc "-- name: X :kind" header to a handler registered by
+// the test; any other query fails, so a test notices when a handler runs a
+// query it did not expect.
+type fakeDB struct {
+	mu       sync.Mutex
+	handlers map[string]fakeQuery
+	calls    []fakeCall
+}
+
+// fakeQuery answers one named query. Its rows are structs in the column
+// order sqlc scans them in, or single values for one-column results. For an
+// exec query only the number of rows matters, as the rows affected.
+type fakeQuery func(args []driver.Value) (rows []any, err error)
+
+type fakeCall struct {
+	name string
+	args []driver.Value
+}
+
+var (
+	fakeDBs   sync.Map
+	fakeDBSeq atomic.Int64
+)
+
+func init() {
+	sql.Register("chirpyfake", fakeDriver{})
+}
+
+// fakeConfig returns a testConfig whose queries run against a fakeDB.
+func fakeConfig(t *testing.T) (*apiConfig, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{handlers: map[string]fakeQuery{}}
+	name := fmt.Sprintf("fake-%d", fakeDBSeq.Add(1))
+	fakeDBs.Store(name, fake)
+	db, err := sql.Open("chirpyfake", name)
+	if err != nil {
+		t.Fatalf("unexpected error opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeDBs.Delete(name)
+	})
+
+	cfg := testConfig(t)
+	cfg.db = db
+	cfg.queries = database.New(db)
+	cfg.revocations = newTokenRevocations(cfg.queries)
+	return cfg, fake
+}
+
+// on registers the handler for the named query, replacing any earlier one.
+func (f *fakeDB) on(name string, q fakeQuery) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[name] = q
+}
+
+// called returns the arguments of every call to the named query so far.
+func (f *fakeDB) called(name string) [][]driver.Value {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out [][]driver.Value
+	for _, c := range f.calls {
+		if c.name == name {
+			out = append(out, c.args)
+		}
+	}
+	return out
+}
+
+func (f *fakeDB) run(query string, named []driver.NamedValue) ([]any, error) {
+	name := queryName(query)
+	args := make([]driver.Value, 0, len(named))
+	for _, a := range named {
+		args = append(args, a.Value)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fakeCall{name: name, args: args})
+	q, ok := f.handlers[name]
+	if !ok {
+		return nil, fmt.Errorf("fakedb: unexpected query %s", name)
+	}
+	return q(args)
+}
+
+// queryName reads X out of the "-- name: X :kind" line sqlc puts first.
+func queryName(query string) string {
+	line, _, _ := strings.Cut(query, "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "--" || fields[1] != "name:" {
+		return line
+	}
+	return fields[2]
+}
+
+// argUUID reads back a uuid.UUID or uuid.NullUUID query argument.
+func argUUID(t *testing.T, v driver.Value) uuid.UUID {
+	t.Helper()
+	if v == nil {
+		return uuid.Nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		t.Fatalf("expected a uuid argument, got %T", v)
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		t.Fatalf("unexpected error parsing uuid argument: %v", err)
+	}
+	return id
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	f, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("fakedb: unknown database %q", name)
+	}
+	return &fakeConn{db: f.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakedb: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return newFakeRows(rows)
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(rows)), nil
+}
+
+// fakeTx does nothing: handlers under test see their writes straight away,
+// and a test that cares about rollback checks which queries ran instead.
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func newFakeRows(rows []any) (*fakeRows, error) {
+	out := &fakeRows{}
+	for _, row := range rows {
+		values, err := rowValues(row)
+		if err != nil {
+			return nil, err
+		}
+		out.values = append(out.values, values)
+	}
+	if len(out.values) > 0 {
+		for i := range out.values[0] {
+			out.columns = append(out.columns, fmt.Sprintf("c%d", i))
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// rowValues flattens a row into driver values: a struct gives one column per
+// field, anything else a single column.
+func rowValues(row any) ([]driver.Value, error) {
+	v := reflect.ValueOf(row)
+	if _, ok := row.(time.Time); ok || v.Kind() != reflect.Struct {
+		value, err := columnValue(v)
+		return []driver.Value{value}, err
+	}
+	if _, ok := row.(driver.Valuer); ok {
+		value, err := columnValue(v)
+		return []driver.Value{value}, err
+	}
+	values := make([]driver.Value, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		value, err := columnValue(v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func columnValue(v reflect.Value) (driver.Value, error) {
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	switch v.Kind() {
+	case reflect.Int32:
+		return v.Int(), nil
+	case reflect.Slice:
+		return pq.Array(v.Interface()).Value()
+	}
+	return v.Interface(), nil
+}
@@ -1,165 +1,217 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"sort"
-	"strings"
 	"time"
 
-	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
 	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/moderation"
 	"github.com/google/uuid"
 )
 
 type newChirp struct {
-	Body   string `json:"body"`
-	UserId string `json:"user_id"`
+	Body          string     `json:"body"`
+	UserId        string     `json:"user_id"`
+	ParentChirpID *uuid.UUID `json:"parent_chirp_id,omitempty"`
 }
 type chirpResponse struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserId    uuid.UUID `json:"user_id"`
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Body          string     `json:"body"`
+	UserId        uuid.UUID  `json:"user_id"`
+	ParentChirpID *uuid.UUID `json:"parent_chirp_id,omitempty"`
+	ReplyCount    int64      `json:"reply_count"`
+	LikeCount     int64      `json:"like_count"`
+	LikedByMe     bool       `json:"liked_by_me"`
+	Deleted       bool       `json:"deleted,omitempty"`
+	Hidden        bool       `json:"hidden,omitempty"`
 }
 
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	userID := principalFromContext(r.Context()).UserID
 
-	// --- Authenticate user ---
-	tokenString, err := auth.GetBearerToken(r.Header)
+	// --- Suspended users cannot post ---
+	author, err := cfg.queries.GetUserByID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "missing or invalid authorization header", http.StatusUnauthorized)
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "invalid or expired token", err)
 		return
 	}
-
-	userID, err := auth.ValidateJWT(tokenString, cfg.JWTSecret)
-	if err != nil {
-		http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+	if author.SuspendedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeAccountSuspended, "account is suspended", nil)
+		return
+	}
+	if !author.EmailVerifiedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeEmailNotVerified, "email address is not verified", nil)
 		return
 	}
 
 	// --- Decode request body ---
 	var in newChirp
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "invalid JSON", err)
 		return
 	}
 
-	// --- Validate chirp body length ---
-	if len(in.Body) > 140 {
-		http.Error(w, "chirp is too long", http.StatusBadRequest)
+	// --- Validate and clean chirp body ---
+	moderated, err := cfg.validateChirpBody(r.Context(), in.Body)
+	if errors.Is(err, errChirpTooLong) || errors.Is(err, errChirpRejected) {
+		respondWithChirpBodyError(w, err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "could not create chirp", err)
 		return
 	}
 
-	// --- Clean bad words ---
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+	// --- Make sure the parent exists when this is a reply ---
+	var parentID uuid.NullUUID
+	if in.ParentChirpID != nil {
+		parent, err := cfg.queries.GetChirp(r.Context(), *in.ParentChirpID)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithValidationErrors(w, "Invalid chirp", []fieldError{{"parent_chirp_id", "not_found", "Parent chirp not found"}})
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to retrieve parent chirp", err)
+			return
+		}
+		if parent.DeletedAt.Valid {
+			respondWithValidationErrors(w, "Invalid chirp", []fieldError{{"parent_chirp_id", "deleted", "Cannot reply to a deleted chirp"}})
+			return
+		}
+		parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
 	}
-	cleanedBody := getCleanedBody(in.Body, badWords)
 
-	// --- Create chirp in database ---
-	chirp, err := cfg.queries.CreateChirp(r.Context(), database.CreateChirpParams{
-		ID:     uuid.New(),
-		Body:   cleanedBody,
-		UserID: userID, // ✅ use user ID from JWT, not request body
+	// --- Create chirp and its tags/mentions in database ---
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "could not create chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+		ID:            uuid.New(),
+		Body:          moderated.Body,
+		UserID:        userID, // ✅ use user ID from JWT, not request body
+		ParentChirpID: parentID,
 	})
 	if err != nil {
-		http.Error(w, "could not create chirp", http.StatusInternalServerError)
+		respondWithError(w, http.StatusInternalServerError, "could not create chirp", err)
+		return
+	}
+	if err := saveChirpEntities(r.Context(), qtx, chirp.ID, chirp.Body); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "could not create chirp", err)
+		return
+	}
+	if err := flagChirp(r.Context(), qtx, chirp.ID, moderated.Flagged); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "could not create chirp", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "could not create chirp", err)
 		return
 	}
 
 	// --- Map DB model to response ---
-	resp := chirpResponse{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserID,
-	}
+	resp := newChirpResponse(chirp)
 
 	// --- Send response ---
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+const maxChirpLength = 140
+
+var (
+	errChirpTooLong  = errors.New("chirp is too long")
+	errChirpRejected = errors.New("chirp contains words that are not allowed")
+)
+
+// validateChirpBody applies the rules every stored chirp body must pass and
+// runs it through the moderation word list. The returned error is
+// errChirpTooLong or errChirpRejected for bad input; anything else is a
+// failure to load the word list.
+func (cfg *apiConfig) validateChirpBody(ctx context.Context, body string) (moderation.Result, error) {
+	if len(body) > maxChirpLength {
+		return moderation.Result{}, errChirpTooLong
+	}
+	filter, err := cfg.wordFilter(ctx)
+	if err != nil {
+		return moderation.Result{}, err
+	}
+	res := filter.Check(body)
+	if len(res.Rejected) > 0 {
+		return res, errChirpRejected
 	}
+	return res, nil
 }
 
-// Helper to replace bad words with ****
-func getCleanedBody(body string, badWords map[string]struct{}) string {
-	words := strings.Split(body, " ")
-	for i, w := range words {
-		if _, exists := badWords[strings.ToLower(w)]; exists {
-			words[i] = "****"
-		}
+// respondWithChirpBodyError reports a validateChirpBody input error against
+// the body field.
+func respondWithChirpBodyError(w http.ResponseWriter, err error) {
+	code := "rejected"
+	if errors.Is(err, errChirpTooLong) {
+		code = "too_long"
 	}
-	return strings.Join(words, " ")
+	respondWithValidationErrors(w, "Invalid chirp", []fieldError{{Field: "body", Code: code, Message: err.Error()}})
+}
+
+type chirpPage struct {
+	Chirps     []chirpResponse `json:"chirps"`
+	NextCursor string          `json:"next_cursor,omitempty"`
 }
 
-// handler function to get all chirps
+// handler function to get a page of chirps
 func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, r *http.Request) {
-	// declare what chirps is first and error value to be changed later
-	var chirps []database.Chirp
-	var err error
+	limit, cursor, err := parsePageParams(r)
+	if err != nil {
+		respondWithInputError(w, "Invalid query parameters", err)
+		return
+	}
 
 	// check for author id in URL
-	authId := r.URL.Query().Get("author_id")
-	if authId == "" {
-		// If no author ID is provided, return all chirps
-		chirps, err = cfg.queries.GetAllChirps(r.Context())
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirps", err)
-			return
-		}
-	} else {
-		// If author ID is provided, return chirps for that author
+	var authorID uuid.NullUUID
+	if authId := r.URL.Query().Get("author_id"); authId != "" {
 		uid, err := uuid.Parse(authId)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "invalid authorId", err)
-			return
-		}
-
-		chirps, err = cfg.queries.GetChirpsByAuthor(r.Context(), uid)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirps", err)
+			respondWithValidationErrors(w, "Invalid query parameters", []fieldError{{"author_id", "invalid", "Author ID must be a UUID"}})
 			return
 		}
+		authorID = uuid.NullUUID{UUID: uid, Valid: true}
 	}
 
-	// check for sort query parameter
-	sortParam := r.URL.Query().Get("sort")
-	if sortParam == "desc" {
-		// sort descending by CreatedAt
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
+	cursorCreatedAt, cursorID := cursor.seekArgs()
+
+	// fetch one extra row so we know whether there is another page
+	var chirps []database.Chirp
+	if r.URL.Query().Get("sort") == "desc" {
+		chirps, err = cfg.queries.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        limit + 1,
 		})
 	} else {
 		// default is ascending
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
+		chirps, err = cfg.queries.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        limit + 1,
 		})
 	}
-
-	// make an array of chirpResponses to hold the chirps
-	resp := make([]chirpResponse, 0, len(chirps))
-
-	// loop through all chirps from the database
-	for _, c := range chirps {
-		resp = append(resp, chirpResponse{
-			ID:        c.ID,
-			CreatedAt: c.CreatedAt,
-			UpdatedAt: c.UpdatedAt,
-			Body:      c.Body,
-			UserId:    c.UserID,
-		})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirps", err)
+		return
 	}
 
-	// respond with the newly created JSON structs
-	respondWithJSON(w, http.StatusOK, resp)
+	cfg.respondWithChirpPage(w, r, chirps, limit, optionalUserID(r))
 }
 
 func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
@@ -186,35 +238,17 @@ func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
 
 	}
 	// make a response so the chirp has something to be loaded into
-	resp := chirpResponse{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserID,
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	chirps, err := cfg.chirpResponses(r.Context(), []database.Chirp{chirp}, optionalUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirp", err)
 		return
 	}
+	respondWithJSON(w, http.StatusOK, chirps[0])
 }
 
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
-	// get tokekn for the user
-	userToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Failed to Get token", err)
-		return
-	}
-
 	// get user id from token
-	userId, err := auth.ValidateJWT(userToken, cfg.JWTSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
-		return
-	}
+	userId := principalFromContext(r.Context()).UserID
 
 	// Extract chirpID from the URL
 	chirpId := r.PathValue("chirpID")
@@ -222,7 +256,7 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 	// convert to uuid
 	uid, err := uuid.Parse(chirpId)
 	if err != nil {
-		http.Error(w, "invalid chirpID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "invalid chirpID", err)
 		return
 	}
 
@@ -239,8 +273,23 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if chirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+
 	// Delete the chirp
-	if err := cfg.queries.RemoveChirp(r.Context(), uid); err != nil {
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	if err := deleteChirp(r.Context(), cfg.queries.WithTx(tx), uid); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete chirp", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete chirp", err)
 		return
 	}
@@ -248,3 +297,30 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 	// Respond with no content
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// deleteChirp removes a chirp. Chirps with replies become tombstones so the
+// thread stays intact. A tombstone takes several statements, so q should
+// belong to a transaction.
+func deleteChirp(ctx context.Context, q *database.Queries, id uuid.UUID) error {
+	replies, err := q.CountChirpReplies(ctx, id)
+	if err != nil {
+		return err
+	}
+	if replies > 0 {
+		return tombstoneChirp(ctx, q, id)
+	}
+	return q.RemoveChirp(ctx, id)
+}
+
+// tombstoneChirp blanks a chirp that still has replies and drops its
+// revision history, tags and mentions, so the deleted text is not kept
+// around anywhere.
+func tombstoneChirp(ctx context.Context, q *database.Queries, id uuid.UUID) error {
+	if err := q.DeleteChirpRevisions(ctx, id); err != nil {
+		return err
+	}
+	if err := saveChirpEntities(ctx, q, id, ""); err != nil {
+		return err
+	}
+	return q.TombstoneChirp(ctx, id)
+}
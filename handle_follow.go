@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type followResponse struct {
+	ID         uuid.UUID `json:"id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followPage struct {
+	Users      []followResponse `json:"users"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID, followeeID, ok := cfg.followTarget(w, r)
+	if !ok {
+		return
+	}
+
+	// make sure the user being followed actually exists
+	if _, err := cfg.queries.GetUserByID(r.Context(), followeeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve user", err)
+		return
+	}
+
+	err := cfg.queries.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to follow user", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID, followeeID, ok := cfg.followTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.queries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to unfollow user", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// followTarget reads the {userID} the caller wants to follow or unfollow.
+// It writes the error response itself when ok is false.
+func (cfg *apiConfig) followTarget(w http.ResponseWriter, r *http.Request) (followerID, followeeID uuid.UUID, ok bool) {
+	followerID = principalFromContext(r.Context()).UserID
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid userID", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if followeeID == followerID {
+		respondWithError(w, http.StatusBadRequest, "You cannot follow yourself", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return followerID, followeeID, true
+}
+
+func (cfg *apiConfig) handlerGetFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.respondWithFollowPage(w, r, func(p database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+		return cfg.queries.ListFollowers(r.Context(), p)
+	})
+}
+
+func (cfg *apiConfig) handlerGetFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.respondWithFollowPage(w, r, func(p database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+		rows, err := cfg.queries.ListFollowing(r.Context(), database.ListFollowingParams(p))
+		if err != nil {
+			return nil, err
+		}
+		out := make([]database.ListFollowersRow, 0, len(rows))
+		for _, row := range rows {
+			out = append(out, database.ListFollowersRow(row))
+		}
+		return out, nil
+	})
+}
+
+// respondWithFollowPage handles the parts shared by the followers and
+// following listings: path and paging parameters, and the page response.
+func (cfg *apiConfig) respondWithFollowPage(w http.ResponseWriter, r *http.Request, list func(database.ListFollowersParams) ([]database.ListFollowersRow, error)) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid userID", err)
+		return
+	}
+	limit, cursor, err := parsePageParams(r)
+	if err != nil {
+		respondWithInputError(w, "Invalid query parameters", err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.seekArgs()
+
+	rows, err := list(database.ListFollowersParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve users", err)
+		return
+	}
+
+	page := followPage{Users: make([]followResponse, 0, len(rows))}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.FollowedAt, ID: last.ID})
+	}
+	for _, row := range rows {
+		page.Users = append(page.Users, followResponse{
+			ID:         row.ID,
+			FollowedAt: row.FollowedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
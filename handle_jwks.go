@@ -0,0 +1,11 @@
+package main
+
+import "net/http"
+
+// handlerJWKS publishes the public keys access tokens can be verified with
+// so other services do not need Chirpy's secret. The list is empty when
+// tokens are signed with the HS256 secret.
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.JWTKeys.JWKS())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, chirpID, ok := cfg.likeTarget(w, r)
+	if !ok {
+		return
+	}
+
+	// liking twice is a no-op thanks to ON CONFLICT DO NOTHING
+	err := cfg.queries.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to like chirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, chirpID, ok := cfg.likeTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.queries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlike chirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// likeTarget loads the {chirpID} the caller wants to like or unlike. It
+// writes the error response itself when ok is false.
+func (cfg *apiConfig) likeTarget(w http.ResponseWriter, r *http.Request) (userID, chirpID uuid.UUID, ok bool) {
+	userID = principalFromContext(r.Context()).UserID
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirpID", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	chirp, err := cfg.queries.GetChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && chirp.DeletedAt.Valid) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirp", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, chirpID, true
+}
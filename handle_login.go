@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -18,13 +19,15 @@ type loginRequest struct {
 }
 
 type loginResponse struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token,omitempty"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	Token         string    `json:"token"`
+	RefreshToken  string    `json:"refresh_token,omitempty"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
 }
 
 func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
@@ -34,27 +37,82 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	// decode the http.Request into logreq
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&logreq); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Failed to retrieve login request", err)
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve login request", err)
 		return
 	}
 	// make sure email is valid
 	if strings.TrimSpace(logreq.Email) == "" {
-		http.Error(w, "Email is required", http.StatusBadRequest)
+		respondWithValidationErrors(w, "Invalid login request", []fieldError{{"email", "required", "Email is required"}})
 		return
 	}
 	// make sure password is set and not default
 	if logreq.Password == "unset" {
-		http.Error(w, "Password is required", http.StatusBadRequest)
+		respondWithValidationErrors(w, "Invalid login request", []fieldError{{"password", "required", "Password is required"}})
 		return
 	}
-	getUser, err := cfg.queries.GetUserByEmail(r.Context(), logreq.Email)
+
+	// throttling is checked before the password so a blocked client learns
+	// nothing from its guesses
+	ipWait, err := cfg.ipLoginRetryAfter(r.Context(), r)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if ipWait > 0 {
+		cfg.recordLoginAttempt(r.Context(), r, logreq.Email, uuid.Nil, loginThrottled)
+		respondTooManyAttempts(w, ipWait)
+		return
+	}
+
+	// the account row stays locked until the failed count is updated, so
+	// concurrent guesses are checked against each other's failures
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	getUser, err := qtx.GetUserByEmailForUpdate(r.Context(), logreq.Email)
+	if err != nil {
+		cfg.recordLoginAttempt(r.Context(), r, logreq.Email, uuid.Nil, loginFailure)
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidCredentials, "Incorrect email or password", err)
+		return
+	}
+	if cfg.accountThrottled(w, r, getUser) {
 		return
 	}
 
 	if err := auth.CheckPasswordHash(logreq.Password, getUser.HashedPassword); err != nil {
-		http.Error(w, "Incorrect email or password", http.StatusUnauthorized)
+		cfg.recordLoginAttempt(r.Context(), r, logreq.Email, getUser.ID, loginFailure)
+		if err := cfg.recordFailedLogin(r.Context(), qtx, getUser.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidCredentials, "Incorrect email or password", nil)
+		return
+	}
+	cfg.recordLoginAttempt(r.Context(), r, logreq.Email, getUser.ID, loginSuccess)
+	// with two-factor authentication on, failures are only forgiven once the
+	// second factor is right too, or a known password would reset the count
+	// between every round of code guesses
+	if getUser.FailedLoginCount > 0 && !getUser.TotpEnabledAt.Valid {
+		if err := qtx.ResetFailedLogins(r.Context(), getUser.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if getUser.SuspendedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeAccountSuspended, "Account is suspended", nil)
 		return
 	}
 	const maxExpiry = time.Hour
@@ -66,39 +124,44 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// make the refresh token to use somehow?
-	refreshtoken, err := auth.MakeRefreshToken()
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Something went wrong", err)
+	// with two-factor authentication on, the password only earns a challenge
+	if getUser.TotpEnabledAt.Valid {
+		cfg.startMFAChallenge(w, r, getUser, expiry)
 		return
 	}
+	cfg.respondWithLogin(w, r, getUser, expiry)
+}
 
-	now := time.Now()
-	expiresAt := now.Add(60 * 24 * time.Hour)
-	_, err = cfg.queries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshtoken,
-		CreatedAt: now,
-		UpdatedAt: now,
-		UserID:    getUser.ID,
-		ExpiresAt: expiresAt,
-	})
+// respondWithLogin starts a new session for a fully authenticated user and
+// responds with its access and refresh tokens.
+func (cfg *apiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, getUser database.User, expiry time.Duration) {
+	// every login starts a new refresh token family
+	sessionID := uuid.New()
+	refreshtoken, err := issueRefreshToken(r.Context(), cfg.queries, r, getUser.ID, sessionID, sql.NullTime{})
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Something went wrong", err)
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
 		return
 	}
 
-	token, err := auth.MakeJWT(getUser.ID, cfg.JWTSecret, expiry)
+	claims := auth.Claims{
+		Role:         getUser.Role,
+		SessionID:    sessionID.String(),
+		TokenVersion: getUser.TokenVersion,
+	}
+	token, err := auth.MakeJWTWithClaims(getUser.ID, claims, cfg.JWTKeys, expiry)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create JWT", err)
 		return
 	}
 	respondWithJSON(w, http.StatusOK, loginResponse{
-		ID:           getUser.ID,
-		CreatedAt:    getUser.CreatedAt,
-		UpdatedAt:    getUser.UpdatedAt,
-		Email:        getUser.Email,
-		Token:        token,
-		RefreshToken: refreshtoken, // maybe
-		IsChirpyRed:  getUser.IsChirpyRed,
+		ID:            getUser.ID,
+		CreatedAt:     getUser.CreatedAt,
+		UpdatedAt:     getUser.UpdatedAt,
+		Email:         getUser.Email,
+		Token:         token,
+		RefreshToken:  refreshtoken, // maybe
+		IsChirpyRed:   getUser.IsChirpyRed,
+		Role:          getUser.Role,
+		EmailVerified: getUser.EmailVerifiedAt.Valid,
 	})
 }
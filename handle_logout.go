@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerLogout revokes the access token it is called with and ends the
+// login session the token belongs to, so neither the access token nor the
+// session's refresh token can be used again. Called with a personal access
+// token, it revokes that token.
+func (cfg *apiConfig) handlerLogout(w http.ResponseWriter, r *http.Request) {
+	caller := principalFromContext(r.Context())
+	userID, claims := caller.UserID, caller.Claims
+
+	if caller.TokenType == tokenTypePersonal {
+		tokenID, err := uuid.Parse(claims.PersonalTokenID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to log out", err)
+			return
+		}
+		_, err = cfg.queries.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{ID: tokenID, UserID: userID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to log out", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := cfg.revocations.revoke(r.Context(), userID, claims); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to log out", err)
+		return
+	}
+	if sessionID, err := uuid.Parse(claims.SessionID); err == nil {
+		_, err := cfg.queries.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+			UserID:   userID,
+			FamilyID: sessionID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to log out", err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
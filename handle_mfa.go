@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/totp"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer      = "Chirpy"
+	mfaChallengeTTL = 5 * time.Minute
+	// maxMFAAttempts caps guesses per challenge. Wrong codes also count as
+	// failed logins, so starting new challenges doesn't get around the
+	// account's backoff and lockout.
+	maxMFAAttempts    = 5
+	recoveryCodeCount = 10
+)
+
+type mfaChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// secondFactorRequest carries either a current TOTP code or one of the
+// recovery codes handed out at enrollment.
+type secondFactorRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// startMFAChallenge answers a correct password for a user with two-factor
+// authentication on. The challenge token is opaque and stored hashed, so it
+// can never be mistaken for an access token.
+func (cfg *apiConfig) startMFAChallenge(w http.ResponseWriter, r *http.Request, user database.User, expiry time.Duration) {
+	token, err := auth.MakeRandomToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	expiresAt := time.Now().Add(mfaChallengeTTL)
+	err = cfg.queries.CreateMFAChallenge(r.Context(), database.CreateMFAChallengeParams{
+		TokenHash:        auth.HashToken(token),
+		UserID:           user.ID,
+		ExpiresAt:        expiresAt,
+		AccessTtlSeconds: int32(expiry / time.Second),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, mfaChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   expiresAt,
+	})
+}
+
+// handlerLoginMFA exchanges an MFA challenge token and a second factor for
+// the tokens a normal login returns.
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+		secondFactorRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	challenge, err := qtx.GetMFAChallengeForUpdate(r.Context(), auth.HashToken(req.MFAToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidMFAToken, "Invalid or expired MFA token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if challenge.UsedAt.Valid || !challenge.ExpiresAt.After(time.Now()) || challenge.Attempts >= maxMFAAttempts {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidMFAToken, "Invalid or expired MFA token", nil)
+		return
+	}
+
+	user, err := qtx.GetUserByIDForUpdate(r.Context(), challenge.UserID)
+	if err != nil {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidMFAToken, "Invalid or expired MFA token", err)
+		return
+	}
+	if cfg.accountThrottled(w, r, user) {
+		return
+	}
+	ok, err := checkSecondFactor(r.Context(), qtx, user, req.secondFactorRequest)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if !ok {
+		if err := qtx.RecordMFAChallengeAttempt(r.Context(), challenge.TokenHash); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+		cfg.failSecondFactor(w, r, tx, qtx, user)
+		return
+	}
+	if err := qtx.UseMFAChallenge(r.Context(), challenge.TokenHash); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if user.FailedLoginCount > 0 {
+		if err := qtx.ResetFailedLogins(r.Context(), user.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+
+	if user.SuspendedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeAccountSuspended, "Account is suspended", nil)
+		return
+	}
+	cfg.respondWithLogin(w, r, user, time.Duration(challenge.AccessTtlSeconds)*time.Second)
+}
+
+// failSecondFactor counts a wrong second factor as a failed login against
+// user, commits tx and answers with 401.
+func (cfg *apiConfig) failSecondFactor(w http.ResponseWriter, r *http.Request, tx *sql.Tx, qtx *database.Queries, user database.User) {
+	cfg.recordLoginAttempt(r.Context(), r, user.Email, user.ID, loginFailure)
+	if err := cfg.recordFailedLogin(r.Context(), qtx, user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	respondWithProblem(w, http.StatusUnauthorized, codeInvalidCode, "Invalid code", nil)
+}
+
+// checkSecondFactor verifies a TOTP code or spends a recovery code. Each
+// TOTP code is accepted once and each recovery code is single use.
+func checkSecondFactor(ctx context.Context, q *database.Queries, user database.User, req secondFactorRequest) (bool, error) {
+	if !user.TotpEnabledAt.Valid {
+		return false, nil
+	}
+	switch {
+	case req.Code != "":
+		counter, ok := totp.Validate(req.Code, user.TotpSecret.String, time.Now(), user.TotpLastCounter)
+		if !ok {
+			return false, nil
+		}
+		// the conditional update stops two requests spending the same code
+		n, err := q.AdvanceTOTPCounter(ctx, database.AdvanceTOTPCounterParams{
+			ID:              user.ID,
+			TotpLastCounter: counter,
+		})
+		return n == 1, err
+	case req.RecoveryCode != "":
+		n, err := q.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashToken(normalizeRecoveryCode(req.RecoveryCode)),
+		})
+		return n == 1, err
+	}
+	return false, nil
+}
+
+// mfaCaller loads the calling user. It writes the error response itself
+// when ok is false.
+func (cfg *apiConfig) mfaCaller(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	user, err := cfg.queries.GetUserByID(r.Context(), principalFromContext(r.Context()).UserID)
+	if err != nil {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// handlerEnrollTOTP creates a new secret for the caller. It is not used for
+// login until confirmed with a code from the authenticator app.
+func (cfg *apiConfig) handlerEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.mfaCaller(w, r)
+	if !ok {
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enroll", err)
+		return
+	}
+	err = cfg.queries.SetPendingTOTPSecret(r.Context(), database.SetPendingTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enroll", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"secret":      secret,
+		"otpauth_uri": totp.ProvisioningURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// handlerConfirmTOTP turns two-factor authentication on once the caller
+// proves their app generates the right codes, and hands out recovery codes.
+// The recovery codes are only ever shown here.
+func (cfg *apiConfig) handlerConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.mfaCaller(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusBadRequest, "Two-factor enrollment has not been started", nil)
+		return
+	}
+	counter, ok := totp.Validate(req.Code, user.TotpSecret.String, time.Now(), 0)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid code", nil)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	err = qtx.EnableTOTP(r.Context(), database.EnableTOTPParams{
+		ID:              user.ID,
+		TotpLastCounter: counter,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+		return
+	}
+	if err := replaceRecoveryCodes(r.Context(), qtx, user.ID, hashes); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string][]string{
+		"recovery_codes": codes,
+	})
+}
+
+// handlerDisableTOTP turns two-factor authentication off. A second factor
+// is required so a stolen access token alone cannot do it, and wrong ones
+// count towards the same backoff and lockout as logins.
+func (cfg *apiConfig) handlerDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.mfaCaller(w, r)
+	if !ok {
+		return
+	}
+	var req secondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is not enabled", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	user, err = qtx.GetUserByIDForUpdate(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+		return
+	}
+	if cfg.accountThrottled(w, r, user) {
+		return
+	}
+	ok, err = checkSecondFactor(r.Context(), qtx, user, req)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+		return
+	}
+	if !ok {
+		cfg.failSecondFactor(w, r, tx, qtx, user)
+		return
+	}
+	if err := qtx.DisableTOTP(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+		return
+	}
+	if user.FailedLoginCount > 0 {
+		if err := qtx.ResetFailedLogins(r.Context(), user.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns n codes formatted for people to copy, like
+// abcd-efgh-ijkl-mnop, and the hashes to store for them.
+func generateRecoveryCodes(n int) (codes, hashes []string, err error) {
+	for i := 0; i < n; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		hashes = append(hashes, auth.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode undoes the formatting people add or drop when
+// typing a recovery code back in.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *database.Queries, userID uuid.UUID, hashes []string) error {
+	if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	return q.AddRecoveryCodes(ctx, database.AddRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	})
+}
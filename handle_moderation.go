@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/moderation"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type moderationWordRequest struct {
+	Word   string `json:"word"`
+	Action string `json:"action"`
+}
+
+type moderationWordResponse struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Word      string    `json:"word"`
+	Action    string    `json:"action"`
+}
+
+func newModerationWordResponse(w database.ModerationWord) moderationWordResponse {
+	return moderationWordResponse{
+		ID:        w.ID,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+		Word:      w.Word,
+		Action:    w.Action,
+	}
+}
+
+func (cfg *apiConfig) handlerListModerationWords(w http.ResponseWriter, r *http.Request) {
+	words, err := cfg.queries.ListModerationWords(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve moderation words", err)
+		return
+	}
+	resp := make([]moderationWordResponse, 0, len(words))
+	for _, word := range words {
+		resp = append(resp, newModerationWordResponse(word))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerCreateModerationWord(w http.ResponseWriter, r *http.Request) {
+	var req moderationWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "invalid JSON", err)
+		return
+	}
+	// store the normalized form so the unique constraint catches variants
+	word := moderation.Normalize(req.Word)
+	if word == "" {
+		respondWithValidationErrors(w, "Invalid moderation word", []fieldError{{"word", "invalid", "Word must contain at least one letter"}})
+		return
+	}
+	if req.Action == "" {
+		req.Action = string(moderation.ActionMask)
+	}
+	action, err := moderation.ParseAction(req.Action)
+	if err != nil {
+		respondWithValidationErrors(w, "Invalid moderation word", []fieldError{{"action", "invalid", "Action must be one of mask, reject or flag"}})
+		return
+	}
+
+	created, err := cfg.queries.CreateModerationWord(r.Context(), database.CreateModerationWordParams{
+		Word:   word,
+		Action: string(action),
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			respondWithError(w, http.StatusConflict, "word is already on the list", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to create moderation word", err)
+		return
+	}
+	cfg.invalidateWordFilter()
+	respondWithJSON(w, http.StatusCreated, newModerationWordResponse(created))
+}
+
+func (cfg *apiConfig) handlerUpdateModerationWord(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("wordID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid wordID", err)
+		return
+	}
+	var req moderationWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "invalid JSON", err)
+		return
+	}
+	action, err := moderation.ParseAction(req.Action)
+	if err != nil {
+		respondWithValidationErrors(w, "Invalid moderation word", []fieldError{{"action", "invalid", "Action must be one of mask, reject or flag"}})
+		return
+	}
+
+	updated, err := cfg.queries.UpdateModerationWord(r.Context(), database.UpdateModerationWordParams{
+		ID:     id,
+		Action: string(action),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Moderation word not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update moderation word", err)
+		return
+	}
+	cfg.invalidateWordFilter()
+	respondWithJSON(w, http.StatusOK, newModerationWordResponse(updated))
+}
+
+func (cfg *apiConfig) handlerDeleteModerationWord(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("wordID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid wordID", err)
+		return
+	}
+	deleted, err := cfg.queries.DeleteModerationWord(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete moderation word", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Moderation word not found", nil)
+		return
+	}
+	cfg.invalidateWordFilter()
+	w.WriteHeader(http.StatusNoContent)
+}
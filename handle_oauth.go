@@ -0,0 +1,484 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	oauthCodeTTL = 5 * time.Minute
+	// oauthAccessTTL is the lifetime of tokens issued to clients; clients
+	// get no refresh token and send the user through authorization again.
+	oauthAccessTTL = time.Hour
+
+	authMethodNone        = "none"
+	authMethodClientBasic = "client_secret_basic"
+)
+
+type oauthClientResponse struct {
+	ClientID                string    `json:"client_id"`
+	ClientSecret            string    `json:"client_secret,omitempty"`
+	ClientName              string    `json:"client_name"`
+	RedirectURIs            []string  `json:"redirect_uris"`
+	Scope                   string    `json:"scope"`
+	TokenEndpointAuthMethod string    `json:"token_endpoint_auth_method"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+func newOAuthClientResponse(c database.OauthClient) oauthClientResponse {
+	method := authMethodClientBasic
+	if !c.SecretHash.Valid {
+		method = authMethodNone
+	}
+	return oauthClientResponse{
+		ClientID:                c.ID.String(),
+		ClientName:              c.Name,
+		RedirectURIs:            c.RedirectUris,
+		Scope:                   strings.Join(c.Scopes, " "),
+		TokenEndpointAuthMethod: method,
+		CreatedAt:               c.CreatedAt,
+	}
+}
+
+// validRedirectURI accepts absolute https URLs without a fragment, and
+// plain http only for loopback addresses used during development.
+func validRedirectURI(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return false
+}
+
+// handlerRegisterOAuthClient registers a third-party app owned by the
+// caller, loosely following RFC 7591. Confidential clients get a secret
+// that is shown only in this response; public clients ("none") must use PKCE.
+func (cfg *apiConfig) handlerRegisterOAuthClient(w http.ResponseWriter, r *http.Request) {
+	ownerID := principalFromContext(r.Context()).UserID
+
+	var req struct {
+		ClientName              string   `json:"client_name"`
+		RedirectURIs            []string `json:"redirect_uris"`
+		Scope                   string   `json:"scope"`
+		TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+
+	var errs []fieldError
+	req.ClientName = strings.TrimSpace(req.ClientName)
+	if req.ClientName == "" {
+		errs = append(errs, fieldError{"client_name", "required", "Client name is required"})
+	}
+	if len(req.RedirectURIs) == 0 {
+		errs = append(errs, fieldError{"redirect_uris", "required", "At least one redirect URI is required"})
+	}
+	for _, uri := range req.RedirectURIs {
+		if !validRedirectURI(uri) {
+			errs = append(errs, fieldError{"redirect_uris", "invalid", "Redirect URIs must be https, or http on localhost, without a fragment: " + uri})
+		}
+	}
+	scopes, err := auth.ParseScope(req.Scope)
+	if err != nil {
+		errs = append(errs, fieldError{"scope", "invalid", err.Error()})
+	} else if len(scopes) == 0 {
+		errs = append(errs, fieldError{"scope", "required", "At least one scope is required"})
+	}
+	if req.TokenEndpointAuthMethod == "" {
+		req.TokenEndpointAuthMethod = authMethodClientBasic
+	}
+	if req.TokenEndpointAuthMethod != authMethodClientBasic && req.TokenEndpointAuthMethod != authMethodNone {
+		errs = append(errs, fieldError{"token_endpoint_auth_method", "invalid", "Must be client_secret_basic or none"})
+	}
+	if len(errs) > 0 {
+		respondWithValidationErrors(w, "Invalid client registration", errs)
+		return
+	}
+
+	var secret string
+	var secretHash sql.NullString
+	if req.TokenEndpointAuthMethod == authMethodClientBasic {
+		secret, err = auth.MakeRandomToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.queries.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      ownerID,
+		Name:         req.ClientName,
+		SecretHash:   secretHash,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       scopes,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to register client", err)
+		return
+	}
+	resp := newOAuthClientResponse(client)
+	resp.ClientSecret = secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// handlerListOAuthClients lists the clients the caller registered.
+func (cfg *apiConfig) handlerListOAuthClients(w http.ResponseWriter, r *http.Request) {
+	ownerID := principalFromContext(r.Context()).UserID
+
+	clients, err := cfg.queries.ListOAuthClientsByOwner(r.Context(), ownerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list clients", err)
+		return
+	}
+	resp := make([]oauthClientResponse, 0, len(clients))
+	for _, c := range clients {
+		resp = append(resp, newOAuthClientResponse(c))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// handlerDeleteOAuthClient removes one of the caller's clients along with
+// its outstanding authorization codes. Access tokens already issued stay
+// valid until they expire.
+func (cfg *apiConfig) handlerDeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	ownerID := principalFromContext(r.Context()).UserID
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid client ID", err)
+		return
+	}
+
+	deleted, err := cfg.queries.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{ID: clientID, OwnerID: ownerID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete client", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Client not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeRequest holds the parameters of an authorization request
+// (RFC 6749 section 4.1.1, RFC 7636 section 4.3).
+type authorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// checkAuthorizeRequest validates an authorization request against the
+// client's registration. It fills in the redirect URI when the client has
+// only one and the request left it out, and returns the requested scopes.
+func (cfg *apiConfig) checkAuthorizeRequest(r *http.Request, req *authorizeRequest) (database.OauthClient, []string, error) {
+	clientID, err := uuid.Parse(req.ClientID)
+	if err != nil {
+		return database.OauthClient{}, nil, fieldError{"client_id", "invalid", "Unknown client"}
+	}
+	client, err := cfg.queries.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.OauthClient{}, nil, fieldError{"client_id", "invalid", "Unknown client"}
+	}
+	if err != nil {
+		return database.OauthClient{}, nil, err
+	}
+
+	if req.RedirectURI == "" && len(client.RedirectUris) == 1 {
+		req.RedirectURI = client.RedirectUris[0]
+	}
+	if !slices.Contains(client.RedirectUris, req.RedirectURI) {
+		return database.OauthClient{}, nil, fieldError{"redirect_uri", "invalid", "Redirect URI is not registered for this client"}
+	}
+	if req.ResponseType != "code" {
+		return database.OauthClient{}, nil, fieldError{"response_type", "invalid", "Response type must be code"}
+	}
+	scopes, err := auth.ParseScope(req.Scope)
+	if err != nil {
+		return database.OauthClient{}, nil, fieldError{"scope", "invalid", err.Error()}
+	}
+	if len(scopes) == 0 {
+		return database.OauthClient{}, nil, fieldError{"scope", "required", "At least one scope is required"}
+	}
+	for _, s := range scopes {
+		if !slices.Contains(client.Scopes, s) {
+			return database.OauthClient{}, nil, fieldError{"scope", "invalid", "Client is not registered for scope " + s}
+		}
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		return database.OauthClient{}, nil, fieldError{"code_challenge_method", "invalid", "Code challenge method must be S256"}
+	}
+	if req.CodeChallenge == "" && !client.SecretHash.Valid {
+		return database.OauthClient{}, nil, fieldError{"code_challenge", "required", "Public clients must use PKCE"}
+	}
+	return client, scopes, nil
+}
+
+type consentScope struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// handlerGetAuthorize validates an authorization request and describes what
+// the user is being asked to consent to. The user answers with
+// handlerPostAuthorize.
+func (cfg *apiConfig) handlerGetAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := authorizeRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+	client, scopes, err := cfg.checkAuthorizeRequest(r, &req)
+	if err != nil {
+		respondWithInputError(w, "Invalid authorization request", err)
+		return
+	}
+
+	consent := make([]consentScope, 0, len(scopes))
+	for _, s := range scopes {
+		d, _ := auth.ScopeDescription(s)
+		consent = append(consent, consentScope{Scope: s, Description: d})
+	}
+	respondWithJSON(w, http.StatusOK, struct {
+		ClientID    string         `json:"client_id"`
+		ClientName  string         `json:"client_name"`
+		RedirectURI string         `json:"redirect_uri"`
+		Scopes      []consentScope `json:"scopes"`
+		State       string         `json:"state,omitempty"`
+	}{
+		ClientID:    client.ID.String(),
+		ClientName:  client.Name,
+		RedirectURI: req.RedirectURI,
+		Scopes:      consent,
+		State:       req.State,
+	})
+}
+
+// handlerPostAuthorize records the user's answer to a consent request and
+// returns where to send the browser: back to the client with a code, or
+// with access_denied.
+func (cfg *apiConfig) handlerPostAuthorize(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+
+	var req struct {
+		authorizeRequest
+		Approve bool `json:"approve"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+	// the token request only has to repeat redirect_uri when it was sent
+	// here rather than filled in from the registration
+	redirectURISent := req.RedirectURI != ""
+	client, scopes, err := cfg.checkAuthorizeRequest(r, &req.authorizeRequest)
+	if err != nil {
+		respondWithInputError(w, "Invalid authorization request", err)
+		return
+	}
+
+	user, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeAccountSuspended, "Account is suspended", nil)
+		return
+	}
+
+	redirect, _ := url.Parse(req.RedirectURI)
+	params := redirect.Query()
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	if !req.Approve {
+		params.Set("error", "access_denied")
+		redirect.RawQuery = params.Encode()
+		respondWithJSON(w, http.StatusOK, map[string]string{"redirect_to": redirect.String()})
+		return
+	}
+
+	code, err := auth.MakeRandomToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	err = cfg.queries.CreateOAuthAuthorizationCode(r.Context(), database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:        auth.HashToken(code),
+		ClientID:        client.ID,
+		UserID:          userID,
+		RedirectUri:     req.RedirectURI,
+		Scopes:          scopes,
+		CodeChallenge:   sql.NullString{String: req.CodeChallenge, Valid: req.CodeChallenge != ""},
+		ExpiresAt:       time.Now().Add(oauthCodeTTL),
+		RedirectUriSent: redirectURISent,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	params.Set("code", code)
+	redirect.RawQuery = params.Encode()
+	respondWithJSON(w, http.StatusOK, map[string]string{"redirect_to": redirect.String()})
+}
+
+// respondWithOAuthError sends an error in the token endpoint's format
+// (RFC 6749 section 5.2).
+func respondWithOAuthError(w http.ResponseWriter, code int, oauthErr, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeOAuthProblem(w, oauthProblem{
+		problem:          newProblem(w, code, oauthErr, description),
+		Error:            oauthErr,
+		ErrorDescription: description,
+	})
+}
+
+// oauthProblem is a problem that also carries the RFC 6749 section 5.2
+// members, so OAuth client libraries can read it too.
+type oauthProblem struct {
+	problem
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// writeOAuthProblem logs and sends p.
+func writeOAuthProblem(w http.ResponseWriter, p oauthProblem) {
+	logProblem(p.problem, nil)
+	writeProblemJSON(w, p.Status, p)
+}
+
+// handlerOAuthToken exchanges an authorization code for a scoped access
+// token. Confidential clients authenticate with HTTP Basic; public clients
+// prove possession of the code with their PKCE verifier.
+func (cfg *apiConfig) handlerOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", "Malformed form body")
+		return
+	}
+	if gt := r.PostForm.Get("grant_type"); gt != "authorization_code" {
+		respondWithOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Only authorization_code is supported")
+		return
+	}
+
+	clientIDParam, secret, basic := r.BasicAuth()
+	if basic {
+		clientIDParam, _ = url.QueryUnescape(clientIDParam)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientIDParam = r.PostForm.Get("client_id")
+	}
+	clientID, err := uuid.Parse(clientIDParam)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Unknown client")
+		return
+	}
+	client, err := cfg.queries.GetOAuthClient(r.Context(), clientID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if err != nil || (client.SecretHash.Valid &&
+		subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1) {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		}
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	defer tx.Rollback()
+	q := cfg.queries.WithTx(tx)
+
+	codeHash := auth.HashToken(r.PostForm.Get("code"))
+	grant, err := q.GetOAuthAuthorizationCodeForUpdate(r.Context(), codeHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	redirectURI := r.PostForm.Get("redirect_uri")
+	if err != nil || grant.UsedAt.Valid || time.Now().After(grant.ExpiresAt) || grant.ClientID != client.ID ||
+		(grant.RedirectUriSent || redirectURI != "") && grant.RedirectUri != redirectURI {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+		return
+	}
+	if grant.CodeChallenge.Valid && !auth.CheckPKCE(r.PostForm.Get("code_verifier"), grant.CodeChallenge.String) {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "PKCE verification failed")
+		return
+	}
+	if err := q.UseOAuthAuthorizationCode(r.Context(), codeHash); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	user, err := q.GetUserByID(r.Context(), grant.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Account is suspended")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+
+	scope := strings.Join(grant.Scopes, " ")
+	accessToken, err := auth.MakeJWTWithClaims(user.ID, auth.Claims{
+		ClientID:     client.ID.String(),
+		Scope:        scope,
+		TokenVersion: user.TokenVersion,
+	}, cfg.JWTKeys, oauthAccessTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create JWT", err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+		Scope       string `json:"scope"`
+	}{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oauthAccessTTL.Seconds()),
+		Scope:       scope,
+	})
+}
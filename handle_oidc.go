@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/oidc"
+)
+
+const (
+	// oidcLoginTTL is how long a user has to finish at the provider
+	oidcLoginTTL = 10 * time.Minute
+	// oidcAccessTTL matches the longest access token handlerLogin issues
+	oidcAccessTTL = time.Hour
+	// oidcStateCookie binds a login to the browser that started it
+	oidcStateCookie = "chirpy_oidc_state"
+)
+
+// loadOIDCProviders reads the identity providers named in OIDC_PROVIDERS, a
+// comma separated list. Each name N is configured by OIDC_N_ISSUER,
+// OIDC_N_CLIENT_ID, OIDC_N_CLIENT_SECRET, OIDC_N_REDIRECT_URL and optionally
+// OIDC_N_SCOPES (space separated), with N upper-cased.
+func loadOIDCProviders() (map[string]*oidc.Provider, error) {
+	providers := map[string]*oidc.Provider{}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		cfg := oidc.Config{
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+			return nil, fmt.Errorf("%sISSUER, %sCLIENT_ID and %sREDIRECT_URL must be set", prefix, prefix, prefix)
+		}
+		providers[name] = oidc.NewProvider(cfg, client)
+	}
+	return providers, nil
+}
+
+// handlerOIDCLogin sends the user to the identity provider. The state,
+// nonce and PKCE verifier are kept server side, keyed by the hash of the
+// state the provider hands back to the callback. The hash is also set as a
+// cookie, so the callback only completes in the browser that started the
+// login and an attacker can't log a victim in to the attacker's account.
+func (cfg *apiConfig) handlerOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	provider, ok := cfg.oidcProviders[name]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown identity provider", nil)
+		return
+	}
+
+	if err := cfg.queries.DeleteExpiredOIDCLoginStates(r.Context()); err != nil {
+		log.Printf("Failed to delete expired OIDC login states: %v", err)
+	}
+
+	state, err := auth.MakeRandomToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	nonce, err := auth.MakeRandomToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, challenge)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Identity provider is unavailable", err)
+		return
+	}
+	err = cfg.queries.CreateOIDCLoginState(r.Context(), database.CreateOIDCLoginStateParams{
+		StateHash:    auth.HashToken(state),
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	cfg.setOIDCStateCookie(w, name, auth.HashToken(state), int(oidcLoginTTL.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handlerOIDCCallback finishes a provider login and responds like
+// handlerLogin: with tokens, an MFA challenge when the account has
+// two-factor authentication on, or a 429 while the account is locked out or
+// backing off.
+func (cfg *apiConfig) handlerOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	provider, ok := cfg.oidcProviders[name]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown identity provider", nil)
+		return
+	}
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		respondWithError(w, http.StatusUnauthorized, "Identity provider refused the login", errors.New(e))
+		return
+	}
+	state, code := q.Get("state"), q.Get("code")
+	var errs []fieldError
+	if state == "" {
+		errs = append(errs, fieldError{"state", "required", "State is required"})
+	}
+	if code == "" {
+		errs = append(errs, fieldError{"code", "required", "Code is required"})
+	}
+	if len(errs) > 0 {
+		respondWithValidationErrors(w, "Invalid callback", errs)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(auth.HashToken(state))) != 1 {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidLoginState, "Invalid or expired login state", err)
+		return
+	}
+	cfg.setOIDCStateCookie(w, name, "", -1)
+
+	// the state is deleted as it is read, so a callback can't be replayed
+	login, err := cfg.queries.TakeOIDCLoginState(r.Context(), auth.HashToken(state))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if err != nil || login.Provider != name || time.Now().After(login.ExpiresAt) {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidLoginState, "Invalid or expired login state", err)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Failed to verify identity", err)
+		return
+	}
+
+	user, created, err := cfg.userForIdentity(r.Context(), name, identity)
+	var conflict identityConflictError
+	if errors.As(err, &conflict) {
+		respondWithError(w, conflict.status, conflict.msg, nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if created && !user.EmailVerifiedAt.Valid {
+		if err := cfg.sendVerificationEmail(r.Context(), user.ID, user.Email); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+		}
+	}
+
+	// a provider login is no way around a lockout or the password backoff,
+	// which someone guessing the password may have caused
+	if cfg.accountThrottled(w, r, user) {
+		return
+	}
+	cfg.recordLoginAttempt(r.Context(), r, user.Email, user.ID, loginSuccess)
+	if user.SuspendedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeAccountSuspended, "Account is suspended", nil)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		cfg.startMFAChallenge(w, r, user, oidcAccessTTL)
+		return
+	}
+	cfg.respondWithLogin(w, r, user, oidcAccessTTL)
+}
+
+// setOIDCStateCookie sets or, with a negative maxAge, clears the cookie
+// holding the state hash of a login with provider.
+func (cfg *apiConfig) setOIDCStateCookie(w http.ResponseWriter, provider, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/api/oidc/" + provider + "/callback",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   cfg.platform != "dev",
+		// Lax still sends it on the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// identityConflictError is a login the provider vouched for that can't be
+// matched to an account.
+type identityConflictError struct {
+	status int
+	msg    string
+}
+
+func (e identityConflictError) Error() string { return e.msg }
+
+// userForIdentity returns the account linked to a provider identity. An
+// identity seen for the first time is linked to the account with the same
+// email, but only when both the provider and Chirpy have verified that
+// address; otherwise anyone able to claim an address at either end could
+// take over the account. With no such account a new one is created, with no
+// password so it can only sign in through the provider until one is set by
+// a password reset.
+func (cfg *apiConfig) userForIdentity(ctx context.Context, provider string, id *oidc.IDToken) (database.User, bool, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, false, err
+	}
+	defer tx.Rollback()
+	q := cfg.queries.WithTx(tx)
+
+	user, err := q.GetUserByIdentity(ctx, database.GetUserByIdentityParams{Provider: provider, Subject: id.Subject})
+	if err == nil {
+		return user, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, false, err
+	}
+	if id.Email == "" {
+		return database.User{}, false, identityConflictError{http.StatusBadRequest, "Identity provider did not share an email address"}
+	}
+
+	created := false
+	user, err = q.GetUserByEmail(ctx, id.Email)
+	switch {
+	case err == nil:
+		if !id.EmailVerified || !user.EmailVerifiedAt.Valid {
+			return database.User{}, false, identityConflictError{http.StatusConflict, "An account with this email already exists; verify the email address on both accounts to link them"}
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		verifiedAt := sql.NullTime{Time: time.Now(), Valid: id.EmailVerified}
+		user, err = q.CreateExternalUser(ctx, database.CreateExternalUserParams{Email: id.Email, EmailVerifiedAt: verifiedAt})
+		if err != nil {
+			return database.User{}, false, err
+		}
+		created = true
+		// the configured admin account becomes admin if there isn't one yet
+		if id.EmailVerified && cfg.adminEmail != "" && user.Email == cfg.adminEmail {
+			promoted, err := q.BootstrapAdmin(ctx, user.Email)
+			if err != nil {
+				return database.User{}, false, err
+			}
+			if promoted > 0 {
+				user.Role = auth.RoleAdmin
+			}
+		}
+	default:
+		return database.User{}, false, err
+	}
+
+	err = q.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  id.Subject,
+		Email:    id.Email,
+	})
+	if err != nil {
+		return database.User{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return database.User{}, false, err
+	}
+	return user, created, nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/mailer"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/throttle"
+)
+
+const (
+	passwordResetTTL = 30 * time.Minute
+	// passwordResetSendTimeout bounds the background work of issuing and
+	// mailing a reset token after the request has been answered.
+	passwordResetSendTimeout = 30 * time.Second
+	// maxPasswordResetSends bounds how many reset emails are sent at once,
+	// so a burst of requests can't pile up goroutines and mail connections.
+	maxPasswordResetSends = 8
+	// passwordResetWindow is how far back reset requests are counted.
+	passwordResetWindow = time.Hour
+)
+
+var (
+	// passwordResetEmailPolicy stops one inbox being flooded with resets.
+	passwordResetEmailPolicy = throttle.Policy{FreeAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	// passwordResetIPPolicy stops one client mailing many addresses.
+	passwordResetIPPolicy = throttle.Policy{FreeAttempts: 10, BaseDelay: 10 * time.Second, MaxDelay: time.Hour}
+)
+
+// handlerRequestPasswordReset mails a reset token when the email belongs to
+// an account. The response is the same either way, and the token is issued
+// after responding so timing does not give the answer away either.
+// Requests are throttled per address and per IP whether or not the account
+// exists, for the same reason.
+func (cfg *apiConfig) handlerRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+	if req.Email == "" {
+		respondWithValidationErrors(w, "Invalid password reset request", []fieldError{{"email", "required", "Email is required"}})
+		return
+	}
+
+	wait, err := cfg.passwordResetRetryAfter(r.Context(), r, req.Email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to request password reset", err)
+		return
+	}
+	if wait > 0 {
+		respondRetryAfter(w, http.StatusTooManyRequests, wait, "Too many password reset requests, try again later")
+		return
+	}
+
+	select {
+	case cfg.resetSends <- struct{}{}:
+	default:
+		respondRetryAfter(w, http.StatusServiceUnavailable, time.Second, "Too many password resets in progress, try again later")
+		return
+	}
+	err = cfg.queries.RecordPasswordResetRequest(r.Context(), database.RecordPasswordResetRequestParams{
+		Email: passwordResetKey(req.Email),
+		Ip:    clientIP(r),
+	})
+	if err != nil {
+		<-cfg.resetSends
+		respondWithError(w, http.StatusInternalServerError, "Failed to request password reset", err)
+		return
+	}
+
+	go func(email string) {
+		defer func() { <-cfg.resetSends }()
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+		defer cancel()
+		if err := cfg.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("Failed to send password reset: %v", err)
+		}
+	}(req.Email)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// passwordResetRetryAfter returns how long until another reset may be
+// requested for email from the request's IP, whichever limit is further off.
+func (cfg *apiConfig) passwordResetRetryAfter(ctx context.Context, r *http.Request, email string) (time.Duration, error) {
+	now := time.Now()
+	since := now.Add(-passwordResetWindow)
+	byEmail, err := cfg.queries.GetRecentPasswordResetRequestsByEmail(ctx, database.GetRecentPasswordResetRequestsByEmailParams{
+		Email:     passwordResetKey(email),
+		CreatedAt: since,
+	})
+	if err != nil {
+		return 0, err
+	}
+	byIP, err := cfg.queries.GetRecentPasswordResetRequestsByIP(ctx, database.GetRecentPasswordResetRequestsByIPParams{
+		Ip:        clientIP(r),
+		CreatedAt: since,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return max(
+		passwordResetEmailPolicy.RetryAfter(int(byEmail.Requests), byEmail.LastRequest, now),
+		passwordResetIPPolicy.RetryAfter(int(byIP.Requests), byIP.LastRequest, now),
+	), nil
+}
+
+// passwordResetKey is the form an address is counted under, so changing
+// its case doesn't get around the limit.
+func passwordResetKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := cfg.queries.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.MakeRandomToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.queries.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for this account. If it was you, send the token below with your new password to POST /api/password-reset/confirm.\n\n%s\n\nIt expires in %s. If it was not you, you can ignore this email.",
+			token, passwordResetTTL),
+	})
+}
+
+// handlerConfirmPasswordReset sets a new password using a reset token. Every
+// session and access token of the account stops working.
+func (cfg *apiConfig) handlerConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	rt, err := qtx.GetPasswordResetTokenForUpdate(r.Context(), auth.HashToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidResetToken, "Invalid or expired reset token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	if rt.UsedAt.Valid || !rt.ExpiresAt.After(time.Now()) {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidResetToken, "Invalid or expired reset token", nil)
+		return
+	}
+
+	user, err := qtx.GetUserByID(r.Context(), rt.UserID)
+	if err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidResetToken, "Invalid or expired reset token", err)
+		return
+	}
+	if !cfg.checkPassword(w, req.Password, user.Email) {
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             rt.UserID,
+		HashedPassword: hash,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	if err := qtx.UsePasswordResetTokens(r.Context(), rt.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	if err := qtx.RevokeAllUserRefreshTokens(r.Context(), rt.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	// personal access tokens don't carry the token version, and one minted
+	// by whoever took over the account must not survive its recovery
+	if err := qtx.RevokeAllPersonalAccessTokens(r.Context(), rt.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	version, err := qtx.BumpUserTokenVersion(r.Context(), rt.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	if err := recordSecurityEvent(r.Context(), qtx, r, rt.UserID, eventPasswordReset, ""); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+	cfg.revocations.setVersion(rt.UserID, version)
+
+	w.WriteHeader(http.StatusNoContent)
+}
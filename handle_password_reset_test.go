@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+)
+
+func TestRequestPasswordResetThrottling(t *testing.T) {
+	tests := []struct {
+		name       string
+		byEmail    int64
+		byIP       int64
+		sendsFull  bool
+		status     int
+		retryAfter string
+	}{
+		{name: "accepted", status: 202},
+		{name: "free requests left", byEmail: 3, byIP: 10, status: 202},
+		{name: "too many for the address", byEmail: 4, status: 429, retryAfter: "60"},
+		{name: "too many from the IP", byIP: 11, status: 429, retryAfter: "10"},
+		{name: "too many sends in progress", sendsFull: true, status: 503, retryAfter: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, fake := fakeConfig(t)
+			now := time.Now()
+			fake.on("GetRecentPasswordResetRequestsByEmail", func(args []driver.Value) ([]any, error) {
+				return []any{database.GetRecentPasswordResetRequestsByEmailRow{Requests: tt.byEmail, LastRequest: now}}, nil
+			})
+			fake.on("GetRecentPasswordResetRequestsByIP", func(args []driver.Value) ([]any, error) {
+				return []any{database.GetRecentPasswordResetRequestsByIPRow{Requests: tt.byIP, LastRequest: now}}, nil
+			})
+			fake.on("RecordPasswordResetRequest", func(args []driver.Value) ([]any, error) { return nil, nil })
+			fake.on("GetUserByEmail", func(args []driver.Value) ([]any, error) { return nil, nil })
+			if tt.sendsFull {
+				for range maxPasswordResetSends {
+					cfg.resetSends <- struct{}{}
+				}
+			}
+
+			req := httptest.NewRequest("POST", "/api/password-reset/request", strings.NewReader(`{"email":" Someone@Example.com"}`))
+			rec := httptest.NewRecorder()
+			cfg.routes().ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d (body %q)", tt.status, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("expected Retry-After %q, got %q", tt.retryAfter, got)
+			}
+
+			recorded := fake.called("RecordPasswordResetRequest")
+			if tt.status != 202 {
+				if len(recorded) != 0 {
+					t.Errorf("expected a refused request not to be counted, got %v", recorded)
+				}
+				return
+			}
+			if want := []driver.Value{"someone@example.com", "192.0.2.1"}; len(recorded) != 1 || !reflect.DeepEqual(recorded[0], want) {
+				t.Errorf("expected the request to be counted as %v, got %v", want, recorded)
+			}
+			// the send runs in the background and gives its slot back
+			deadline := time.Now().Add(time.Second)
+			for len(cfg.resetSends) > 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := len(cfg.resetSends); n != 0 {
+				t.Errorf("expected the send slot to be released, got %d in use", n)
+			}
+		})
+	}
+}
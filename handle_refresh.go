@@ -1,32 +1,145 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
 )
 
+const refreshTokenTTL = 60 * 24 * time.Hour
+
+// issueRefreshToken stores a new refresh token for userID in familyID. Pass
+// uuid.New() to start a new family, as a fresh login does. The family is
+// the session the user sees in /api/sessions; lastUsed is set when the token
+// replaces an older one from the same session.
+func issueRefreshToken(ctx context.Context, q *database.Queries, r *http.Request, userID, familyID uuid.UUID, lastUsed sql.NullTime) (string, error) {
+	refreshtoken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash:  auth.HashRefreshToken(refreshtoken),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		UserID:     userID,
+		ExpiresAt:  now.Add(refreshTokenTTL),
+		FamilyID:   familyID,
+		LastUsedAt: lastUsed,
+		UserAgent:  r.UserAgent(),
+		Ip:         clientIP(r),
+	})
+	if err != nil {
+		return "", err
+	}
+	return refreshtoken, nil
+}
+
+// handlerRefresh trades a refresh token for a new access token and a new
+// refresh token. The presented token is spent. Presenting a token that was
+// already rotated means it has leaked, so the whole family is revoked along
+// with the access tokens issued from it.
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 	gettoken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
 		return
 	}
 
-	getuser, err := cfg.queries.GetUserFromRefreshToken(r.Context(), gettoken)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	current, err := qtx.GetRefreshTokenForUpdate(r.Context(), auth.HashRefreshToken(gettoken))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	if current.ReplacedByHash.Valid {
+		if err := qtx.RevokeRefreshTokenFamily(r.Context(), current.FamilyID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+			return
+		}
+		detail := fmt.Sprintf("family=%s", current.FamilyID)
+		if err := recordSecurityEvent(r.Context(), qtx, r, current.UserID, eventRefreshTokenReuse, detail); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+			return
+		}
+		// access tokens already issued to the leaked session go too
+		if err := cfg.revocations.revokeSessions(r.Context(), current.UserID, []uuid.UUID{current.FamilyID}); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+			return
+		}
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", nil)
+		return
+	}
+	if current.RevokedAt.Valid || !current.ExpiresAt.After(time.Now()) {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", nil)
 		return
 	}
-	token, err := auth.MakeJWT(getuser.ID, cfg.JWTSecret, time.Hour)
+
+	getuser, err := qtx.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
+		return
+	}
+	if getuser.SuspendedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeAccountSuspended, "Account is suspended", nil)
+		return
+	}
+
+	lastUsed := sql.NullTime{Time: time.Now(), Valid: true}
+	refreshtoken, err := issueRefreshToken(r.Context(), qtx, r, getuser.ID, current.FamilyID, lastUsed)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+	err = qtx.MarkRefreshTokenRotated(r.Context(), database.MarkRefreshTokenRotatedParams{
+		TokenHash:      current.TokenHash,
+		ReplacedByHash: sql.NullString{String: auth.HashRefreshToken(refreshtoken), Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	claims := auth.Claims{
+		Role:         getuser.Role,
+		SessionID:    current.FamilyID.String(),
+		TokenVersion: getuser.TokenVersion,
+	}
+	token, err := auth.MakeJWTWithClaims(getuser.ID, claims, cfg.JWTKeys, time.Hour)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create jwt", err)
 		return
 	}
 
-	// If we reach this point, the token is valid and not expired
 	respondWithJSON(w, http.StatusOK, map[string]string{
-		"token": token,
+		"token":         token,
+		"refresh_token": refreshtoken,
 	})
 }
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const maxReportReasonLength = 500
+
+// Moderation actions as recorded in the moderation_actions audit table.
+const (
+	modActionHide      = "hide_chirp"
+	modActionRestore   = "restore_chirp"
+	modActionDelete    = "delete_chirp"
+	modActionSuspend   = "suspend_user"
+	modActionUnsuspend = "unsuspend_user"
+	modActionDismiss   = "dismiss_report"
+	modActionUnlock    = "unlock_user"
+)
+
+type reportRequest struct {
+	Reason string `json:"reason"`
+}
+
+type moderationActionRequest struct {
+	Note string `json:"note"`
+}
+
+type reportResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ChirpID    uuid.UUID  `json:"chirp_id"`
+	Reason     string     `json:"reason"`
+	ReporterID *uuid.UUID `json:"reporter_id,omitempty"`
+	Status     string     `json:"status"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+type reportQueueItem struct {
+	reportResponse
+	ChirpBody   string    `json:"chirp_body"`
+	AuthorID    uuid.UUID `json:"author_id"`
+	ChirpHidden bool      `json:"chirp_hidden"`
+}
+
+type reportPage struct {
+	Reports    []reportQueueItem `json:"reports"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func newReportResponse(r database.ChirpReport) reportResponse {
+	resp := reportResponse{
+		ID:        r.ID,
+		CreatedAt: r.CreatedAt,
+		ChirpID:   r.ChirpID,
+		Reason:    r.Reason,
+		Status:    r.Status,
+	}
+	if r.ReporterID.Valid {
+		resp.ReporterID = &r.ReporterID.UUID
+	}
+	if r.ResolvedAt.Valid {
+		resp.ResolvedAt = &r.ResolvedAt.Time
+	}
+	return resp
+}
+
+func (cfg *apiConfig) handlerReportChirp(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirpID", err)
+		return
+	}
+	var req reportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "invalid JSON", err)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		respondWithValidationErrors(w, "Invalid report", []fieldError{{"reason", "required", "Reason is required"}})
+		return
+	}
+	if len(req.Reason) > maxReportReasonLength {
+		respondWithValidationErrors(w, "Invalid report", []fieldError{{"reason", "too_long", "Reason must be at most " + strconv.Itoa(maxReportReasonLength) + " characters"}})
+		return
+	}
+
+	chirp, err := cfg.queries.GetChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && chirp.DeletedAt.Valid) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirp", err)
+		return
+	}
+
+	report, err := cfg.queries.CreateChirpReport(r.Context(), database.CreateChirpReportParams{
+		ChirpID:    chirpID,
+		Reason:     req.Reason,
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			respondWithError(w, http.StatusConflict, "You have already reported this chirp", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to report chirp", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newReportResponse(report))
+}
+
+// handlerListReports is the moderation queue, oldest report first.
+// ?status= selects open (the default), resolved or dismissed reports.
+func (cfg *apiConfig) handlerListReports(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.moderationActor(w, r); !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = "open"
+	case "open", "resolved", "dismissed":
+	default:
+		respondWithValidationErrors(w, "Invalid query parameters", []fieldError{{"status", "invalid", "Status must be one of open, resolved or dismissed"}})
+		return
+	}
+	limit, cursor, err := parsePageParams(r)
+	if err != nil {
+		respondWithInputError(w, "Invalid query parameters", err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.seekArgs()
+
+	rows, err := cfg.queries.ListChirpReports(r.Context(), database.ListChirpReportsParams{
+		Status:          status,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve reports", err)
+		return
+	}
+
+	page := reportPage{Reports: make([]reportQueueItem, 0, len(rows))}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, row := range rows {
+		page.Reports = append(page.Reports, reportQueueItem{
+			reportResponse: newReportResponse(database.ChirpReport{
+				ID:         row.ID,
+				CreatedAt:  row.CreatedAt,
+				ChirpID:    row.ChirpID,
+				Reason:     row.Reason,
+				ReporterID: row.ReporterID,
+				Status:     row.Status,
+				ResolvedAt: row.ResolvedAt,
+			}),
+			ChirpBody:   row.ChirpBody,
+			AuthorID:    row.AuthorID,
+			ChirpHidden: row.ChirpHiddenAt.Valid,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (cfg *apiConfig) handlerHideChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.moderateChirp(w, r, modActionHide, func(ctx context.Context, q *database.Queries, id uuid.UUID) (bool, error) {
+		n, err := q.HideChirp(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return n > 0, q.ResolveChirpReports(ctx, id)
+	})
+}
+
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.moderateChirp(w, r, modActionRestore, func(ctx context.Context, q *database.Queries, id uuid.UUID) (bool, error) {
+		n, err := q.RestoreChirp(ctx, id)
+		return n > 0, err
+	})
+}
+
+func (cfg *apiConfig) handlerAdminDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.moderateChirp(w, r, modActionDelete, func(ctx context.Context, q *database.Queries, id uuid.UUID) (bool, error) {
+		// tombstoned chirps keep their reports, so close them first
+		if err := q.ResolveChirpReports(ctx, id); err != nil {
+			return false, err
+		}
+		if err := deleteChirp(ctx, q, id); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// moderateChirp runs a moderator action against {chirpID} and records it in
+// the audit table, in one transaction so no action goes unaudited. apply
+// reports whether it changed anything; a no-op, such as hiding a chirp that
+// is already hidden, is answered with 409.
+func (cfg *apiConfig) moderateChirp(w http.ResponseWriter, r *http.Request, action string, apply func(context.Context, *database.Queries, uuid.UUID) (bool, error)) {
+	actorID, ok := cfg.moderationActor(w, r)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirpID", err)
+		return
+	}
+	note, ok := decodeModerationNote(w, r)
+	if !ok {
+		return
+	}
+
+	chirp, err := cfg.queries.GetChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && chirp.DeletedAt.Valid) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirp", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to moderate chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	changed, err := apply(r.Context(), qtx, chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to moderate chirp", err)
+		return
+	}
+	if !changed {
+		respondWithError(w, http.StatusConflict, "Chirp is already in that state", nil)
+		return
+	}
+
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ActorID: uuid.NullUUID{UUID: actorID, Valid: true},
+		Action:  action,
+		ChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+		UserID:  uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		Note:    note,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record moderation action", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to moderate chirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerSuspendUser suspends {userID} and signs them out everywhere:
+// SuspendUser bumps their token version, and their refresh tokens and
+// personal access tokens are revoked, so no existing credential keeps working.
+func (cfg *apiConfig) handlerSuspendUser(w http.ResponseWriter, r *http.Request) {
+	cfg.moderateUser(w, r, modActionSuspend, func(q *database.Queries, ctx context.Context, id uuid.UUID) (database.User, error) {
+		user, err := q.SuspendUser(ctx, id)
+		if err != nil {
+			return database.User{}, err
+		}
+		if err := q.RevokeAllUserRefreshTokens(ctx, id); err != nil {
+			return database.User{}, err
+		}
+		return user, q.RevokeAllPersonalAccessTokens(ctx, id)
+	})
+}
+
+func (cfg *apiConfig) handlerUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	cfg.moderateUser(w, r, modActionUnsuspend, (*database.Queries).UnsuspendUser)
+}
+
+// moderateUser applies a change to {userID}, such as a suspension, and
+// audits it in the same transaction. Only users of a lower role than the
+// caller can be moderated, so moderators can't act on each other or admins.
+func (cfg *apiConfig) moderateUser(w http.ResponseWriter, r *http.Request, action string, apply func(*database.Queries, context.Context, uuid.UUID) (database.User, error)) {
+	actorID, ok := cfg.moderationActor(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid userID", err)
+		return
+	}
+	note, ok := decodeModerationNote(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	target, err := qtx.GetUserByIDForUpdate(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update user", err)
+		return
+	}
+	if auth.RoleAtLeast(target.Role, principalFromContext(r.Context()).Role) {
+		respondWithError(w, http.StatusForbidden, "You cannot moderate a user whose role is the same as or above yours", nil)
+		return
+	}
+
+	updated, err := apply(qtx, r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to update user", err)
+		return
+	}
+
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ActorID: uuid.NullUUID{UUID: actorID, Valid: true},
+		Action:  action,
+		UserID:  uuid.NullUUID{UUID: userID, Valid: true},
+		Note:    note,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record moderation action", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update user", err)
+		return
+	}
+	if updated.TokenVersion != target.TokenVersion {
+		cfg.revocations.setVersion(updated.ID, updated.TokenVersion)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerDismissReport(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := cfg.moderationActor(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid reportID", err)
+		return
+	}
+	note, ok := decodeModerationNote(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := cfg.queries.GetChirpReport(r.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Report not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve report", err)
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to dismiss report", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	n, err := qtx.DismissChirpReport(r.Context(), reportID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to dismiss report", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusConflict, "Report is not open", nil)
+		return
+	}
+
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ActorID:  uuid.NullUUID{UUID: actorID, Valid: true},
+		Action:   modActionDismiss,
+		ChirpID:  uuid.NullUUID{UUID: report.ChirpID, Valid: true},
+		ReportID: uuid.NullUUID{UUID: reportID, Valid: true},
+		Note:     note,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record moderation action", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to dismiss report", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// moderationActor returns the moderator making the request so their action
+// can be audited. Access itself is checked by middlewareRequireRole.
+func (cfg *apiConfig) moderationActor(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	actorID, ok := userIDFromContext(r.Context())
+	if !ok {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", nil)
+		return uuid.Nil, false
+	}
+	return actorID, true
+}
+
+// decodeModerationNote reads the optional {"note": "..."} body sent with a
+// moderation action. An empty body is allowed.
+func decodeModerationNote(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req moderationActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "invalid JSON", err)
+		return "", false
+	}
+	return strings.TrimSpace(req.Note), true
+}
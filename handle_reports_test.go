@@ -0,0 +1,503 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestModerationRejectsBadRequests(t *testing.T) {
+	cfg, _ := fakeConfig(t)
+	handler := cfg.routes()
+
+	user := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{})
+	moderator := testToken(t, cfg.JWTKeys, auth.RoleModerator, auth.Claims{})
+	delegated := testToken(t, cfg.JWTKeys, auth.RoleModerator, auth.Claims{ClientID: uuid.NewString(), Scope: auth.ScopeChirpsWrite})
+	chirpID := uuid.NewString()
+	userID := uuid.NewString()
+	reportID := uuid.NewString()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+		code   string
+		detail string
+	}{
+		{name: "report without token", method: "POST", path: "/api/chirps/" + chirpID + "/report", body: `{"reason":"spam"}`, status: 401, code: codeUnauthenticated},
+		{name: "report invalid chirp", method: "POST", path: "/api/chirps/nope/report", token: user, body: `{"reason":"spam"}`, status: 400, code: codeBadRequest, detail: "invalid chirpID"},
+		{name: "report body not JSON", method: "POST", path: "/api/chirps/" + chirpID + "/report", token: user, body: "{", status: 400, code: codeInvalidBody},
+		{name: "report blank reason", method: "POST", path: "/api/chirps/" + chirpID + "/report", token: user, body: `{"reason":"   "}`, status: 400, code: codeValidationFailed, detail: "Invalid report"},
+		{name: "report reason too long", method: "POST", path: "/api/chirps/" + chirpID + "/report", token: user, body: `{"reason":"` + strings.Repeat("a", maxReportReasonLength+1) + `"}`, status: 400, code: codeValidationFailed, detail: "Invalid report"},
+
+		{name: "queue as user", method: "GET", path: "/admin/reports", token: user, status: 403, code: codeForbidden},
+		{name: "queue with delegated token", method: "GET", path: "/admin/reports", token: delegated, status: 403, code: codeInsufficientScope},
+		{name: "queue bad status", method: "GET", path: "/admin/reports?status=closed", token: moderator, status: 400, code: codeValidationFailed, detail: "Invalid query parameters"},
+		{name: "queue bad cursor", method: "GET", path: "/admin/reports?cursor=nope", token: moderator, status: 400, code: codeValidationFailed},
+
+		{name: "hide as user", method: "POST", path: "/admin/chirps/" + chirpID + "/hide", token: user, status: 403, code: codeForbidden},
+		{name: "hide invalid chirp", method: "POST", path: "/admin/chirps/nope/hide", token: moderator, status: 400, code: codeBadRequest, detail: "invalid chirpID"},
+		{name: "hide note not JSON", method: "POST", path: "/admin/chirps/" + chirpID + "/hide", token: moderator, body: "{", status: 400, code: codeInvalidBody},
+		{name: "delete as user", method: "DELETE", path: "/admin/chirps/" + chirpID, token: user, status: 403, code: codeForbidden},
+
+		{name: "suspend as user", method: "POST", path: "/admin/users/" + userID + "/suspend", token: user, status: 403, code: codeForbidden},
+		{name: "suspend invalid user", method: "POST", path: "/admin/users/nope/suspend", token: moderator, status: 400, code: codeBadRequest, detail: "invalid userID"},
+		{name: "suspend note not JSON", method: "POST", path: "/admin/users/" + userID + "/suspend", token: moderator, body: "[", status: 400, code: codeInvalidBody},
+		{name: "unlock as moderator", method: "POST", path: "/admin/users/" + userID + "/unlock", token: moderator, status: 403, code: codeForbidden},
+
+		{name: "dismiss as user", method: "POST", path: "/admin/reports/" + reportID + "/dismiss", token: user, status: 403, code: codeForbidden},
+		{name: "dismiss invalid report", method: "POST", path: "/admin/reports/nope/dismiss", token: moderator, status: 400, code: codeBadRequest, detail: "invalid reportID"},
+		{name: "dismiss note not JSON", method: "POST", path: "/admin/reports/" + reportID + "/dismiss", token: moderator, body: "{", status: 400, code: codeInvalidBody},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			p := decodeProblem(t, rec)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if p.Code != tt.code {
+				t.Errorf("expected code %q, got %q", tt.code, p.Code)
+			}
+			if tt.detail != "" && p.Detail != tt.detail {
+				t.Errorf("expected detail %q, got %q", tt.detail, p.Detail)
+			}
+		})
+	}
+}
+
+func TestModerationActorRequiresUser(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set(requestIDHeader, "abc")
+	if _, ok := testConfig(t).moderationActor(rec, httptest.NewRequest("GET", "/admin/reports", nil)); ok {
+		t.Fatal("expected no actor without an authenticated user")
+	}
+	if p := decodeProblem(t, rec); rec.Code != 401 || p.Code != codeInvalidToken {
+		t.Errorf("expected 401 %s, got %d %s", codeInvalidToken, rec.Code, p.Code)
+	}
+}
+
+// moderationStore is the state behind the fake queries the moderation
+// handlers run.
+type moderationStore struct {
+	chirps  map[uuid.UUID]*database.Chirp
+	users   map[uuid.UUID]*database.User
+	reports map[uuid.UUID]*database.ChirpReport
+}
+
+func newModerationStore(fake *fakeDB) *moderationStore {
+	s := &moderationStore{
+		chirps:  map[uuid.UUID]*database.Chirp{},
+		users:   map[uuid.UUID]*database.User{},
+		reports: map[uuid.UUID]*database.ChirpReport{},
+	}
+	// the fake runs one query at a time, so handlers need no locking
+	fake.on("GetChirp", func(args []driver.Value) ([]any, error) {
+		if c, ok := s.chirps[uuid.MustParse(args[0].(string))]; ok {
+			return []any{*c}, nil
+		}
+		return nil, nil
+	})
+	fake.on("HideChirp", func(args []driver.Value) ([]any, error) {
+		c := s.chirps[uuid.MustParse(args[0].(string))]
+		if c == nil || c.HiddenAt.Valid {
+			return nil, nil
+		}
+		c.HiddenAt = sql.NullTime{Time: time.Now(), Valid: true}
+		return make([]any, 1), nil
+	})
+	fake.on("RestoreChirp", func(args []driver.Value) ([]any, error) {
+		c := s.chirps[uuid.MustParse(args[0].(string))]
+		if c == nil || !c.HiddenAt.Valid {
+			return nil, nil
+		}
+		c.HiddenAt = sql.NullTime{}
+		return make([]any, 1), nil
+	})
+	fake.on("ResolveChirpReports", func(args []driver.Value) ([]any, error) {
+		chirpID := uuid.MustParse(args[0].(string))
+		for _, r := range s.reports {
+			if r.ChirpID == chirpID && r.Status == "open" {
+				r.Status = "resolved"
+			}
+		}
+		return nil, nil
+	})
+	fake.on("GetChirpReport", func(args []driver.Value) ([]any, error) {
+		if r, ok := s.reports[uuid.MustParse(args[0].(string))]; ok {
+			return []any{*r}, nil
+		}
+		return nil, nil
+	})
+	fake.on("CreateChirpReport", func(args []driver.Value) ([]any, error) {
+		chirpID, reporterID := uuid.MustParse(args[0].(string)), args[2]
+		for _, r := range s.reports {
+			if r.ChirpID == chirpID && r.ReporterID.UUID.String() == reporterID {
+				return nil, &pq.Error{Code: "23505"}
+			}
+		}
+		r := &database.ChirpReport{ID: uuid.New(), CreatedAt: time.Now(), ChirpID: chirpID, Reason: args[1].(string), Status: "open"}
+		r.ReporterID = uuid.NullUUID{UUID: uuid.MustParse(reporterID.(string)), Valid: true}
+		s.reports[r.ID] = r
+		return []any{*r}, nil
+	})
+	fake.on("DismissChirpReport", func(args []driver.Value) ([]any, error) {
+		r := s.reports[uuid.MustParse(args[0].(string))]
+		if r == nil || r.Status != "open" {
+			return nil, nil
+		}
+		r.Status = "dismissed"
+		return make([]any, 1), nil
+	})
+	fake.on("GetUserByIDForUpdate", func(args []driver.Value) ([]any, error) {
+		if u, ok := s.users[uuid.MustParse(args[0].(string))]; ok {
+			return []any{*u}, nil
+		}
+		return nil, nil
+	})
+	fake.on("SuspendUser", func(args []driver.Value) ([]any, error) {
+		u := s.users[uuid.MustParse(args[0].(string))]
+		u.SuspendedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		u.TokenVersion++
+		return []any{*u}, nil
+	})
+	fake.on("RevokeAllUserRefreshTokens", func(args []driver.Value) ([]any, error) { return nil, nil })
+	fake.on("RevokeAllPersonalAccessTokens", func(args []driver.Value) ([]any, error) { return nil, nil })
+	fake.on("CreateModerationAction", func(args []driver.Value) ([]any, error) { return nil, nil })
+	return s
+}
+
+func (s *moderationStore) addChirp(author uuid.UUID) *database.Chirp {
+	c := &database.Chirp{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Body: "buy cheap watches", UserID: author}
+	s.chirps[c.ID] = c
+	return c
+}
+
+func (s *moderationStore) addUser(role string) *database.User {
+	u := &database.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Role: role, TokenVersion: 1}
+	s.users[u.ID] = u
+	return u
+}
+
+// auditArgs builds the arguments CreateModerationAction is expected to get.
+func auditArgs(actor uuid.UUID, action string, chirpID, userID, reportID uuid.UUID, note string) []driver.Value {
+	id := func(id uuid.UUID) driver.Value {
+		if id == uuid.Nil {
+			return nil
+		}
+		return id.String()
+	}
+	return []driver.Value{actor.String(), action, id(chirpID), id(userID), id(reportID), note}
+}
+
+func TestReportChirp(t *testing.T) {
+	cfg, fake := fakeConfig(t)
+	handler := cfg.routes()
+	store := newModerationStore(fake)
+
+	reporter := uuid.New()
+	token, err := auth.MakeJWTWithClaims(reporter, auth.Claims{Role: auth.RoleUser}, cfg.JWTKeys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	chirp := store.addChirp(uuid.New())
+	deleted := store.addChirp(uuid.New())
+	deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	tests := []struct {
+		name    string
+		chirpID uuid.UUID
+		status  int
+	}{
+		{"report", chirp.ID, 201},
+		{"report again", chirp.ID, 409},
+		{"report deleted chirp", deleted.ID, 404},
+		{"report unknown chirp", uuid.New(), 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/chirps/"+tt.chirpID.String()+"/report", strings.NewReader(`{"reason":"  spam  "}`))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d (body %q)", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status != 201 {
+				return
+			}
+
+			var got reportResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("unexpected error decoding response: %v", err)
+			}
+			if got.ChirpID != chirp.ID || got.Reason != "spam" || got.Status != "open" || got.ReporterID == nil || *got.ReporterID != reporter {
+				t.Errorf("expected an open report of %v by %v for spam, got %+v", chirp.ID, reporter, got)
+			}
+		})
+	}
+	if len(store.reports) != 1 {
+		t.Errorf("expected 1 stored report, got %d", len(store.reports))
+	}
+}
+
+func TestListReports(t *testing.T) {
+	cfg, fake := fakeConfig(t)
+	handler := cfg.routes()
+	moderator := testToken(t, cfg.JWTKeys, auth.RoleModerator, auth.Claims{})
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	author := uuid.New()
+	rows := []any{
+		database.ListChirpReportsRow{ID: uuid.New(), CreatedAt: now, ChirpID: uuid.New(), Reason: "spam", Status: "resolved", ResolvedAt: sql.NullTime{Time: now, Valid: true}, ChirpBody: "buy cheap watches", AuthorID: author, ChirpHiddenAt: sql.NullTime{Time: now, Valid: true}},
+		database.ListChirpReportsRow{ID: uuid.New(), CreatedAt: now.Add(time.Minute), ChirpID: uuid.New(), Reason: "rude", Status: "resolved", ChirpBody: "hello", AuthorID: author},
+	}
+	fake.on("ListChirpReports", func(args []driver.Value) ([]any, error) { return rows, nil })
+
+	tests := []struct {
+		query      string
+		wantStatus string
+	}{
+		{"?limit=1", "open"},
+		{"?limit=1&status=resolved", "resolved"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.wantStatus, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/reports"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+moderator)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != 200 {
+				t.Fatalf("expected status 200, got %d (body %q)", rec.Code, rec.Body.String())
+			}
+
+			calls := fake.called("ListChirpReports")
+			if len(calls) != i+1 {
+				t.Fatalf("expected %d queue queries, got %d", i+1, len(calls))
+			}
+			if args := calls[i]; args[0] != tt.wantStatus || args[3] != int64(2) {
+				t.Errorf("expected status %q with a page size of 2, got %v", tt.wantStatus, args)
+			}
+
+			var page reportPage
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatalf("unexpected error decoding response: %v", err)
+			}
+			first := rows[0].(database.ListChirpReportsRow)
+			if len(page.Reports) != 1 {
+				t.Fatalf("expected 1 report, got %d", len(page.Reports))
+			}
+			got := page.Reports[0]
+			if got.ID != first.ID || got.ChirpBody != first.ChirpBody || got.AuthorID != author || !got.ChirpHidden || got.ResolvedAt == nil {
+				t.Errorf("expected report %v on a hidden chirp by %v, got %+v", first.ID, author, got)
+			}
+			if want := encodeCursor(pageCursor{CreatedAt: first.CreatedAt, ID: first.ID}); page.NextCursor != want {
+				t.Errorf("expected next cursor %q, got %q", want, page.NextCursor)
+			}
+		})
+	}
+}
+
+func TestModerateChirp(t *testing.T) {
+	cfg, fake := fakeConfig(t)
+	handler := cfg.routes()
+	store := newModerationStore(fake)
+
+	actor := uuid.New()
+	token, err := auth.MakeJWTWithClaims(actor, auth.Claims{Role: auth.RoleModerator}, cfg.JWTKeys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	author := uuid.New()
+	chirp := store.addChirp(author)
+	store.reports[uuid.New()] = &database.ChirpReport{ChirpID: chirp.ID, Status: "open"}
+
+	// each step runs against the state the previous one left behind
+	tests := []struct {
+		name       string
+		action     string
+		body       string
+		status     int
+		wantHidden bool
+		audit      []driver.Value
+	}{
+		{name: "hide", action: "hide", body: `{"note":" spam "}`, status: 204, wantHidden: true, audit: auditArgs(actor, modActionHide, chirp.ID, author, uuid.Nil, "spam")},
+		{name: "hide hidden chirp", action: "hide", status: 409, wantHidden: true},
+		{name: "restore", action: "restore", status: 204, wantHidden: false, audit: auditArgs(actor, modActionRestore, chirp.ID, author, uuid.Nil, "")},
+		{name: "restore visible chirp", action: "restore", status: 409, wantHidden: false},
+	}
+	audits := 0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/chirps/"+chirp.ID.String()+"/"+tt.action, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d (body %q)", tt.status, rec.Code, rec.Body.String())
+			}
+			if chirp.HiddenAt.Valid != tt.wantHidden {
+				t.Errorf("expected hidden %v, got %v", tt.wantHidden, chirp.HiddenAt.Valid)
+			}
+
+			calls := fake.called("CreateModerationAction")
+			if tt.audit == nil {
+				if len(calls) != audits {
+					t.Errorf("expected no audit row for a no-op, got %v", calls[audits:])
+				}
+				return
+			}
+			audits++
+			if len(calls) != audits {
+				t.Fatalf("expected %d audit rows, got %d", audits, len(calls))
+			}
+			if got := calls[audits-1]; !reflect.DeepEqual(got, tt.audit) {
+				t.Errorf("expected audit row %v, got %v", tt.audit, got)
+			}
+		})
+	}
+	for _, r := range store.reports {
+		if r.Status != "resolved" {
+			t.Errorf("expected hiding to resolve open reports, got status %q", r.Status)
+		}
+	}
+}
+
+func TestModerateUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		actorRole  string
+		targetRole string
+		missing    bool
+		status     int
+	}{
+		{name: "moderator suspends user", actorRole: auth.RoleModerator, targetRole: auth.RoleUser, status: 204},
+		{name: "admin suspends moderator", actorRole: auth.RoleAdmin, targetRole: auth.RoleModerator, status: 204},
+		{name: "moderator suspends moderator", actorRole: auth.RoleModerator, targetRole: auth.RoleModerator, status: 403},
+		{name: "moderator suspends admin", actorRole: auth.RoleModerator, targetRole: auth.RoleAdmin, status: 403},
+		{name: "unknown user", actorRole: auth.RoleModerator, missing: true, status: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, fake := fakeConfig(t)
+			handler := cfg.routes()
+			store := newModerationStore(fake)
+
+			actor := uuid.New()
+			token, err := auth.MakeJWTWithClaims(actor, auth.Claims{Role: tt.actorRole}, cfg.JWTKeys, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error creating token: %v", err)
+			}
+			targetID := uuid.New()
+			if !tt.missing {
+				targetID = store.addUser(tt.targetRole).ID
+			}
+
+			req := httptest.NewRequest("POST", "/admin/users/"+targetID.String()+"/suspend", strings.NewReader(`{"note":"spammer"}`))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d (body %q)", tt.status, rec.Code, rec.Body.String())
+			}
+
+			suspended := tt.status == 204
+			for _, query := range []string{"SuspendUser", "RevokeAllUserRefreshTokens", "RevokeAllPersonalAccessTokens", "CreateModerationAction"} {
+				calls := fake.called(query)
+				if !suspended {
+					if len(calls) != 0 {
+						t.Errorf("expected no %s query, got %v", query, calls)
+					}
+					continue
+				}
+				if len(calls) != 1 {
+					t.Fatalf("expected 1 %s query, got %d", query, len(calls))
+				}
+				if query != "CreateModerationAction" && argUUID(t, calls[0][0]) != targetID {
+					t.Errorf("expected %s for %v, got %v", query, targetID, calls[0])
+				}
+			}
+			if !suspended {
+				return
+			}
+
+			want := auditArgs(actor, modActionSuspend, uuid.Nil, targetID, uuid.Nil, "spammer")
+			if got := fake.called("CreateModerationAction")[0]; !reflect.DeepEqual(got, want) {
+				t.Errorf("expected audit row %v, got %v", want, got)
+			}
+			target := store.users[targetID]
+			if !target.SuspendedAt.Valid {
+				t.Error("expected the user to be suspended")
+			}
+			if v := cfg.revocations.versions[targetID]; v.version != target.TokenVersion {
+				t.Errorf("expected cached token version %d, got %d", target.TokenVersion, v.version)
+			}
+		})
+	}
+}
+
+func TestDismissReport(t *testing.T) {
+	cfg, fake := fakeConfig(t)
+	handler := cfg.routes()
+	store := newModerationStore(fake)
+
+	actor := uuid.New()
+	token, err := auth.MakeJWTWithClaims(actor, auth.Claims{Role: auth.RoleModerator}, cfg.JWTKeys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	report := &database.ChirpReport{ID: uuid.New(), ChirpID: uuid.New(), Reason: "spam", Status: "open"}
+	store.reports[report.ID] = report
+
+	tests := []struct {
+		name     string
+		reportID uuid.UUID
+		status   int
+		audits   int
+	}{
+		{"dismiss", report.ID, 204, 1},
+		{"dismiss again", report.ID, 409, 1},
+		{"dismiss unknown report", uuid.New(), 404, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/reports/"+tt.reportID.String()+"/dismiss", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d (body %q)", tt.status, rec.Code, rec.Body.String())
+			}
+			if calls := fake.called("CreateModerationAction"); len(calls) != tt.audits {
+				t.Errorf("expected %d audit rows, got %d", tt.audits, len(calls))
+			}
+		})
+	}
+
+	if report.Status != "dismissed" {
+		t.Errorf("expected the report to be dismissed, got %q", report.Status)
+	}
+	want := auditArgs(actor, modActionDismiss, report.ChirpID, uuid.Nil, report.ID, "")
+	if got := fake.called("CreateModerationAction")[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected audit row %v, got %v", want, got)
+	}
+}
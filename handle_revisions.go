@@ -0,0 +1,174 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type updateChirpRequest struct {
+	Body string `json:"body"`
+}
+
+type revisionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+func (cfg *apiConfig) handlerUpdateChirp(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+
+	author, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
+		return
+	}
+	if author.SuspendedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeAccountSuspended, "Account is suspended", nil)
+		return
+	}
+	if !author.EmailVerifiedAt.Valid {
+		respondWithProblem(w, http.StatusForbidden, codeEmailNotVerified, "Email address is not verified", nil)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirpID", err)
+		return
+	}
+
+	var req updateChirpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "invalid JSON", err)
+		return
+	}
+	// same rules as handlerCreateChirp
+	moderated, err := cfg.validateChirpBody(r.Context(), req.Body)
+	if errors.Is(err, errChirpTooLong) || errors.Is(err, errChirpRejected) {
+		respondWithChirpBodyError(w, err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update chirp", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	// lock the row so concurrent edits cannot lose a revision
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && chirp.DeletedAt.Valid) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirp", err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "You do not have permission to edit this chirp", nil)
+		return
+	}
+	if time.Since(chirp.CreatedAt) > cfg.chirpEditWindow {
+		respondWithError(w, http.StatusForbidden, "The edit window for this chirp has passed", nil)
+		return
+	}
+
+	if moderated.Body != chirp.Body {
+		if err := qtx.CreateChirpRevision(r.Context(), chirp.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update chirp", err)
+			return
+		}
+		chirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			ID:   chirp.ID,
+			Body: moderated.Body,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update chirp", err)
+			return
+		}
+		if err := saveChirpEntities(r.Context(), qtx, chirp.ID, chirp.Body); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update chirp", err)
+			return
+		}
+		if err := flagChirp(r.Context(), qtx, chirp.ID, moderated.Flagged); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update chirp", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update chirp", err)
+		return
+	}
+
+	chirps, err := cfg.chirpResponses(r.Context(), []database.Chirp{chirp}, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirp", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirps[0])
+}
+
+// handlerGetChirpRevisions lists a chirp's earlier bodies, oldest first.
+// The history of a hidden chirp is only shown to moderators.
+func (cfg *apiConfig) handlerGetChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirpID", err)
+		return
+	}
+
+	chirp, err := cfg.queries.GetChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirp", err)
+		return
+	}
+	// earlier bodies would undo the hide, so only moderators see them
+	if chirp.HiddenAt.Valid && !callerIsModerator(r) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+
+	revisions, err := cfg.queries.ListChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve revisions", err)
+		return
+	}
+
+	resp := make([]revisionResponse, 0, len(revisions))
+	for _, rev := range revisions {
+		resp = append(resp, revisionResponse{
+			ID:         rev.ID,
+			Body:       rev.Body,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: rev.ReplacedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// callerIsModerator reports whether the request was made with a moderator's
+// or admin's own token. Delegated tokens never count.
+func callerIsModerator(r *http.Request) bool {
+	p := principalFromContext(r.Context())
+	return p != nil && !p.Claims.Delegated() && auth.RoleAtLeast(p.Role, auth.RoleModerator)
+}
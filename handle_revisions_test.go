@@ -0,0 +1,45 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestUpdateChirpRequiresActiveAuthor(t *testing.T) {
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	tests := []struct {
+		name   string
+		author database.User
+		code   string
+	}{
+		{"suspended", database.User{SuspendedAt: now, EmailVerifiedAt: now}, codeAccountSuspended},
+		{"unverified email", database.User{}, codeEmailNotVerified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, fake := fakeConfig(t)
+			fake.on("GetUserByID", func(args []driver.Value) ([]any, error) { return []any{tt.author}, nil })
+			token := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{})
+
+			req := httptest.NewRequest("PUT", "/api/chirps/"+uuid.NewString(), strings.NewReader(`{"body":"edited"}`))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			cfg.routes().ServeHTTP(rec, req)
+
+			if p := decodeProblem(t, rec); rec.Code != 403 || p.Code != tt.code {
+				t.Errorf("expected 403 %s, got %d %s", tt.code, rec.Code, p.Code)
+			}
+			if calls := fake.called("GetChirp"); len(calls) != 0 {
+				t.Errorf("expected the chirp not to be looked up, got %d queries", len(calls))
+			}
+		})
+	}
+}
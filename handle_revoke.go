@@ -9,11 +9,11 @@ import (
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 	gettoken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
 		return
 	}
 
-	err = cfg.queries.RevokeRefreshToken(r.Context(), gettoken)
+	err = cfg.queries.RevokeRefreshToken(r.Context(), auth.HashRefreshToken(gettoken))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to revoke token", err)
 		return
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type setRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// handlerSetUserRole lets an admin promote or demote a user. A change of
+// role bumps the user's token version, so access tokens carrying the old
+// role stop working at once and the user refreshes to get the new one.
+func (cfg *apiConfig) handlerSetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid userID", err)
+		return
+	}
+	var req setRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "invalid JSON", err)
+		return
+	}
+	if !auth.ValidRole(req.Role) {
+		respondWithValidationErrors(w, "Invalid role", []fieldError{{"role", "invalid", "Role must be one of user, moderator or admin"}})
+		return
+	}
+
+	updated, err := cfg.queries.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: req.Role,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update role", err)
+		return
+	}
+	cfg.revocations.setVersion(updated.ID, updated.TokenVersion)
+
+	respondWithJSON(w, http.StatusOK, newUserResponse(updated))
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type searchResult struct {
+	chirpResponse
+	Rank float32 `json:"rank"`
+	// Snippet is HTML: the matching parts of the body, escaped, with each
+	// match wrapped in <mark>.
+	Snippet string `json:"snippet"`
+}
+
+type searchPage struct {
+	Results    []searchResult `json:"results"`
+	NextOffset *int32         `json:"next_offset,omitempty"`
+}
+
+// handlerSearchChirps runs a full-text search over chirp bodies.
+// q uses web search syntax, so "quoted phrases", OR and -exclusions work.
+// Results are ordered by rank, which has no stable key to seek on, so this
+// endpoint pages with limit/offset rather than a cursor.
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// every bad parameter is reported at once
+	var errs []fieldError
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		errs = append(errs, fieldError{"q", "required", "Search query is required"})
+	}
+
+	params := database.SearchChirpsParams{
+		Query:    q,
+		PageSize: defaultPageSize,
+	}
+
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			errs = append(errs, fieldError{"limit", "invalid", "Limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+		}
+		params.PageSize = int32(n)
+	}
+	if s := query.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			errs = append(errs, fieldError{"offset", "invalid", "Offset must be a non-negative number"})
+		}
+		params.PageOffset = int32(n)
+	}
+	if s := query.Get("author_id"); s != "" {
+		uid, err := uuid.Parse(s)
+		if err != nil {
+			errs = append(errs, fieldError{"author_id", "invalid", "Author ID must be a UUID"})
+		}
+		params.AuthorID = uuid.NullUUID{UUID: uid, Valid: true}
+	}
+	if s := query.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			errs = append(errs, fieldError{"since", "invalid", "Since must be an RFC 3339 timestamp"})
+		}
+		params.Since = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+	if s := query.Get("until"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			errs = append(errs, fieldError{"until", "invalid", "Until must be an RFC 3339 timestamp"})
+		}
+		params.Until = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+	if len(errs) > 0 {
+		respondWithValidationErrors(w, "Invalid query parameters", errs)
+		return
+	}
+
+	// fetch one extra row so we know whether there is another page
+	limit := params.PageSize
+	params.PageSize++
+	rows, err := cfg.queries.SearchChirps(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to search chirps", err)
+		return
+	}
+
+	var page searchPage
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		next := params.PageOffset + limit
+		page.NextOffset = &next
+	}
+
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, database.Chirp{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			Body:          row.Body,
+			UserID:        row.UserID,
+			ParentChirpID: row.ParentChirpID,
+			DeletedAt:     row.DeletedAt,
+		})
+	}
+	mapped, err := cfg.chirpResponses(r.Context(), chirps, optionalUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to search chirps", err)
+		return
+	}
+
+	page.Results = make([]searchResult, 0, len(rows))
+	for i, row := range rows {
+		page.Results = append(page.Results, searchResult{
+			chirpResponse: mapped[i],
+			Rank:          row.Rank,
+			Snippet:       row.Snippet,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
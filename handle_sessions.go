@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// sessionResponse describes one login session. The ID is the refresh token
+// family, which stays the same across rotations and is not a credential.
+type sessionResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	Current    bool       `json:"current"`
+}
+
+// sessionCaller returns the caller and the session its access token belongs
+// to. The session is uuid.Nil for tokens without a sid claim.
+func sessionCaller(r *http.Request) (userID, sessionID uuid.UUID) {
+	caller := principalFromContext(r.Context())
+	sessionID, _ = uuid.Parse(caller.Claims.SessionID)
+	return caller.UserID, sessionID
+}
+
+func (cfg *apiConfig) handlerListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, sessionID := sessionCaller(r)
+
+	rows, err := cfg.queries.ListUserSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list sessions", err)
+		return
+	}
+
+	sessions := make([]sessionResponse, 0, len(rows))
+	for _, row := range rows {
+		s := sessionResponse{
+			ID:        row.FamilyID,
+			CreatedAt: row.StartedAt,
+			ExpiresAt: row.ExpiresAt,
+			UserAgent: row.UserAgent,
+			IP:        row.Ip,
+			Current:   row.FamilyID == sessionID,
+		}
+		if row.LastUsedAt.Valid {
+			s.LastUsedAt = &row.LastUsedAt.Time
+		}
+		sessions = append(sessions, s)
+	}
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+// handlerRevokeSession ends one of the caller's sessions. Its refresh
+// tokens stop working, and so do the access tokens already issued from it.
+func (cfg *apiConfig) handlerRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := sessionCaller(r)
+	id, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	n, err := cfg.queries.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		UserID:   userID,
+		FamilyID: id,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke session", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Session not found", nil)
+		return
+	}
+	// access tokens already issued from the session carry its sid
+	if err := cfg.revocations.revokeSessions(r.Context(), userID, []uuid.UUID{id}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke session", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerRevokeOtherSessions logs the user out everywhere except the
+// session the request was made from.
+func (cfg *apiConfig) handlerRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, sessionID := sessionCaller(r)
+
+	// the access tokens of the other sessions are denied by sid first, so
+	// none outlives the refresh tokens revoked below
+	sessions, err := cfg.queries.ListUserSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+	var others []uuid.UUID
+	for _, s := range sessions {
+		if s.FamilyID != sessionID && !slices.Contains(others, s.FamilyID) {
+			others = append(others, s.FamilyID)
+		}
+	}
+	if err := cfg.revocations.revokeSessions(r.Context(), userID, others); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	_, err = cfg.queries.RevokeOtherUserSessions(r.Context(), database.RevokeOtherUserSessionsParams{
+		UserID:   userID,
+		FamilyID: sessionID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 30 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+)
+
+type trendingTag struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+func (cfg *apiConfig) handlerGetTagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "invalid tag", nil)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(r)
+	if err != nil {
+		respondWithInputError(w, "Invalid query parameters", err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.seekArgs()
+
+	chirps, err := cfg.queries.ListChirpsByTag(r.Context(), database.ListChirpsByTagParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirps", err)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, chirps, limit, optionalUserID(r))
+}
+
+// handlerGetTrendingTags ranks tags by how many chirps used them within a
+// sliding window ending now, e.g. ?window=6h&limit=5.
+func (cfg *apiConfig) handlerGetTrendingTags(w http.ResponseWriter, r *http.Request) {
+	var errs []fieldError
+	window := defaultTrendingWindow
+	if s := r.URL.Query().Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			errs = append(errs, fieldError{"window", "invalid", "Window must be a positive duration of at most 720h"})
+		}
+		window = d
+	}
+
+	limit := int32(defaultTrendingLimit)
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			errs = append(errs, fieldError{"limit", "invalid", "Limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+		}
+		limit = int32(n)
+	}
+	if len(errs) > 0 {
+		respondWithValidationErrors(w, "Invalid query parameters", errs)
+		return
+	}
+
+	rows, err := cfg.queries.GetTrendingTags(r.Context(), database.GetTrendingTagsParams{
+		WindowSeconds: window.Seconds(),
+		PageSize:      limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve trending tags", err)
+		return
+	}
+
+	resp := make([]trendingTag, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, trendingTag{Tag: row.Tag, ChirpCount: row.ChirpCount})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// handlerGetMyMentions lists chirps that mention the caller, newest first.
+func (cfg *apiConfig) handlerGetMyMentions(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+
+	limit, cursor, err := parsePageParams(r)
+	if err != nil {
+		respondWithInputError(w, "Invalid query parameters", err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.seekArgs()
+
+	chirps, err := cfg.queries.ListMentionsForUser(r.Context(), database.ListMentionsForUserParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve mentions", err)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, chirps, limit, userID)
+}
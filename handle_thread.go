@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// threadNode is a chirp together with the replies made to it.
+type threadNode struct {
+	chirpResponse
+	Replies []*threadNode `json:"replies"`
+}
+
+type threadResponse struct {
+	Root      chirpResponse   `json:"root"`
+	Ancestors []chirpResponse `json:"ancestors"`
+	Chirp     *threadNode     `json:"chirp"`
+}
+
+func (cfg *apiConfig) handlerGetThread(w http.ResponseWriter, r *http.Request) {
+	// Extract chirpID from the URL
+	uid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirpId", err)
+		return
+	}
+
+	chirp, err := cfg.queries.GetChirp(r.Context(), uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve chirp", err)
+		return
+	}
+
+	// ancestors come back root first
+	ancestors, err := cfg.queries.GetChirpAncestors(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve thread", err)
+		return
+	}
+	descendants, err := cfg.queries.GetChirpDescendants(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve thread", err)
+		return
+	}
+
+	all := make([]database.Chirp, 0, len(ancestors)+1+len(descendants))
+	all = append(all, ancestors...)
+	all = append(all, chirp)
+	all = append(all, descendants...)
+	mapped, err := cfg.chirpResponses(r.Context(), all, optionalUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve thread", err)
+		return
+	}
+
+	resp := threadResponse{
+		Root:      mapped[0],
+		Ancestors: mapped[:len(ancestors)],
+		Chirp:     buildReplyTree(mapped[len(ancestors)], mapped[len(ancestors)+1:]),
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// buildReplyTree nests descendants under their parents, keeping the order
+// they come in among siblings. A reply can share its parent's timestamp, so
+// descendants may list it before the chirp it answers.
+func buildReplyTree(root chirpResponse, descendants []chirpResponse) *threadNode {
+	children := map[uuid.UUID][]chirpResponse{}
+	for _, d := range descendants {
+		if d.ParentChirpID != nil {
+			children[*d.ParentChirpID] = append(children[*d.ParentChirpID], d)
+		}
+	}
+
+	rootNode := &threadNode{chirpResponse: root, Replies: []*threadNode{}}
+	stack := []*threadNode{rootNode}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, c := range children[node.ID] {
+			child := &threadNode{chirpResponse: c, Replies: []*threadNode{}}
+			node.Replies = append(node.Replies, child)
+			stack = append(stack, child)
+		}
+	}
+	return rootNode
+}
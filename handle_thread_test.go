@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildReplyTree(t *testing.T) {
+	ids := map[string]uuid.UUID{}
+	chirp := func(name, parent string) chirpResponse {
+		if _, ok := ids[name]; !ok {
+			ids[name] = uuid.New()
+		}
+		c := chirpResponse{ID: ids[name], Body: name}
+		if parent != "" {
+			if _, ok := ids[parent]; !ok {
+				ids[parent] = uuid.New()
+			}
+			p := ids[parent]
+			c.ParentChirpID = &p
+		}
+		return c
+	}
+
+	tests := []struct {
+		name        string
+		descendants [][2]string
+		want        string
+	}{
+		{"no replies", nil, "root"},
+		{"direct replies keep their order", [][2]string{{"a", "root"}, {"b", "root"}}, "root(a,b)"},
+		{"nested replies", [][2]string{{"a", "root"}, {"b", "a"}, {"c", "b"}, {"d", "root"}}, "root(a(b(c)),d)"},
+		{"reply listed before its parent", [][2]string{{"b", "a"}, {"a", "root"}}, "root(a(b))"},
+		{"siblings listed before their parent", [][2]string{{"c", "a"}, {"b", "a"}, {"a", "root"}}, "root(a(c,b))"},
+		{"orphans are dropped", [][2]string{{"a", "root"}, {"b", "elsewhere"}, {"c", ""}}, "root(a)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			descendants := make([]chirpResponse, 0, len(tt.descendants))
+			for _, d := range tt.descendants {
+				descendants = append(descendants, chirp(d[0], d[1]))
+			}
+			got := formatThread(buildReplyTree(chirp("root", ""), descendants))
+			if got != tt.want {
+				t.Errorf("expected tree %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildReplyTreeEmptyReplies(t *testing.T) {
+	root := buildReplyTree(chirpResponse{ID: uuid.New()}, nil)
+	if root.Replies == nil {
+		t.Error("expected an empty, non-nil replies slice so it encodes as []")
+	}
+}
+
+// formatThread writes a tree as body(reply,reply) for comparison.
+func formatThread(n *threadNode) string {
+	if len(n.Replies) == 0 {
+		return n.Body
+	}
+	replies := make([]string, 0, len(n.Replies))
+	for _, r := range n.Replies {
+		replies = append(replies, formatThread(r))
+	}
+	return n.Body + "(" + strings.Join(replies, ",") + ")"
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+)
+
+// handlerGetTimeline returns chirps from everyone the caller follows, newest first.
+func (cfg *apiConfig) handlerGetTimeline(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+
+	limit, cursor, err := parsePageParams(r)
+	if err != nil {
+		respondWithInputError(w, "Invalid query parameters", err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursor.seekArgs()
+
+	// fetch one extra row so we know whether there is another page
+	chirps, err := cfg.queries.GetTimeline(r.Context(), database.GetTimelineParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve timeline", err)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, chirps, limit, userID)
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestTimelineRejectsBadRequests(t *testing.T) {
+	cfg, _ := fakeConfig(t)
+	handler := cfg.routes()
+
+	me := uuid.New()
+	myToken, err := auth.MakeJWTWithClaims(me, auth.Claims{Role: auth.RoleUser}, cfg.JWTKeys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	readOnly := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{ClientID: uuid.NewString(), Scope: auth.ScopeChirpsRead})
+	profileOnly := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{ClientID: uuid.NewString(), Scope: auth.ScopeProfile})
+	other := uuid.NewString()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		code   string
+		detail string
+	}{
+		{name: "timeline without token", method: "GET", path: "/api/timeline", status: 401, code: codeUnauthenticated},
+		{name: "timeline bad limit", method: "GET", path: "/api/timeline?limit=0", token: myToken, status: 400, code: codeValidationFailed, detail: "Invalid query parameters"},
+		{name: "timeline bad cursor", method: "GET", path: "/api/timeline?cursor=nope", token: myToken, status: 400, code: codeValidationFailed, detail: "Invalid query parameters"},
+		{name: "timeline without read scope", method: "GET", path: "/api/timeline", token: profileOnly, status: 403, code: codeInsufficientScope},
+		{name: "followers invalid user", method: "GET", path: "/api/users/nope/followers", status: 400, code: codeBadRequest, detail: "invalid userID"},
+		{name: "followers bad limit", method: "GET", path: "/api/users/" + other + "/followers?limit=101", status: 400, code: codeValidationFailed},
+		{name: "following bad cursor", method: "GET", path: "/api/users/" + other + "/following?cursor=nope", status: 400, code: codeValidationFailed},
+		{name: "follow without token", method: "POST", path: "/api/users/" + other + "/follow", status: 401, code: codeUnauthenticated},
+		{name: "follow with delegated token", method: "POST", path: "/api/users/" + other + "/follow", token: readOnly, status: 403, code: codeInsufficientScope},
+		{name: "follow invalid user", method: "POST", path: "/api/users/nope/follow", token: myToken, status: 400, code: codeBadRequest, detail: "invalid userID"},
+		{name: "follow yourself", method: "POST", path: "/api/users/" + me.String() + "/follow", token: myToken, status: 400, code: codeBadRequest, detail: "You cannot follow yourself"},
+		{name: "unfollow yourself", method: "DELETE", path: "/api/users/" + me.String() + "/follow", token: myToken, status: 400, code: codeBadRequest, detail: "You cannot follow yourself"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			p := decodeProblem(t, rec)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if p.Code != tt.code {
+				t.Errorf("expected code %q, got %q", tt.code, p.Code)
+			}
+			if tt.detail != "" && p.Detail != tt.detail {
+				t.Errorf("expected detail %q, got %q", tt.detail, p.Detail)
+			}
+		})
+	}
+}
+
+func TestGetTimeline(t *testing.T) {
+	cfg, fake := fakeConfig(t)
+	handler := cfg.routes()
+
+	me, followee := uuid.New(), uuid.New()
+	myToken, err := auth.MakeJWTWithClaims(me, auth.Claims{Role: auth.RoleUser}, cfg.JWTKeys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	chirps := make([]any, 0, 3)
+	for i := range 3 {
+		createdAt := now.Add(-time.Duration(i) * time.Minute)
+		chirps = append(chirps, database.Chirp{ID: uuid.New(), CreatedAt: createdAt, UpdatedAt: createdAt, Body: "chirp", UserID: followee})
+	}
+	first, second := chirps[0].(database.Chirp), chirps[1].(database.Chirp)
+
+	fake.on("GetTimeline", func(args []driver.Value) ([]any, error) { return chirps, nil })
+	fake.on("CountRepliesForChirps", func(args []driver.Value) ([]any, error) {
+		return []any{database.CountRepliesForChirpsRow{ParentChirpID: uuid.NullUUID{UUID: first.ID, Valid: true}, ReplyCount: 4}}, nil
+	})
+	fake.on("CountLikesForChirps", func(args []driver.Value) ([]any, error) {
+		return []any{database.CountLikesForChirpsRow{ChirpID: second.ID, LikeCount: 2}}, nil
+	})
+	fake.on("GetLikedChirpIDs", func(args []driver.Value) ([]any, error) { return []any{second.ID}, nil })
+
+	cursor := encodeCursor(pageCursor{CreatedAt: now.Add(-time.Hour), ID: uuid.New()})
+	req := httptest.NewRequest("GET", "/api/timeline?limit=2&cursor="+cursor, nil)
+	req.Header.Set("Authorization", "Bearer "+myToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("expected status 200, got %d (body %q)", rec.Code, rec.Body.String())
+	}
+
+	calls := fake.called("GetTimeline")
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 timeline query, got %d", len(calls))
+	}
+	args := calls[0]
+	if got := argUUID(t, args[0]); got != me {
+		t.Errorf("expected the timeline of %v, got %v", me, got)
+	}
+	c, _ := decodeCursor(cursor)
+	if got, ok := args[1].(time.Time); !ok || !got.Equal(c.CreatedAt) {
+		t.Errorf("expected cursor created_at %v, got %v", c.CreatedAt, args[1])
+	}
+	if got := argUUID(t, args[2]); got != c.ID {
+		t.Errorf("expected cursor id %v, got %v", c.ID, got)
+	}
+	if args[3] != int64(3) {
+		t.Errorf("expected a page size of 3, got %v", args[3])
+	}
+
+	var page chirpPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("unexpected error decoding response: %v", err)
+	}
+	if len(page.Chirps) != 2 || page.Chirps[0].ID != first.ID || page.Chirps[1].ID != second.ID {
+		t.Fatalf("expected chirps %v and %v, got %+v", first.ID, second.ID, page.Chirps)
+	}
+	if want := encodeCursor(pageCursor{CreatedAt: second.CreatedAt, ID: second.ID}); page.NextCursor != want {
+		t.Errorf("expected next cursor %q, got %q", want, page.NextCursor)
+	}
+	if page.Chirps[0].ReplyCount != 4 || page.Chirps[1].LikeCount != 2 {
+		t.Errorf("expected 4 replies and 2 likes, got %d and %d", page.Chirps[0].ReplyCount, page.Chirps[1].LikeCount)
+	}
+	if page.Chirps[0].LikedByMe || !page.Chirps[1].LikedByMe {
+		t.Errorf("expected only the second chirp to be liked by me, got %v and %v", page.Chirps[0].LikedByMe, page.Chirps[1].LikedByMe)
+	}
+}
+
+func TestGetTimelineLastPage(t *testing.T) {
+	cfg, fake := fakeConfig(t)
+	handler := cfg.routes()
+	fake.on("GetTimeline", func(args []driver.Value) ([]any, error) { return nil, nil })
+
+	// a delegated token with chirps:read may read the timeline too
+	token := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{ClientID: uuid.NewString(), Scope: auth.ScopeChirpsRead})
+	req := httptest.NewRequest("GET", "/api/timeline", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("expected status 200, got %d (body %q)", rec.Code, rec.Body.String())
+	}
+
+	if calls := fake.called("GetTimeline"); len(calls) != 1 || calls[0][1] != nil || calls[0][2] != nil || calls[0][3] != int64(defaultPageSize+1) {
+		t.Errorf("expected one first-page query of %d rows, got %v", defaultPageSize+1, calls)
+	}
+	var page map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("unexpected error decoding response: %v", err)
+	}
+	if chirps, ok := page["chirps"].([]any); !ok || len(chirps) != 0 {
+		t.Errorf("expected an empty chirps array, got %v", page["chirps"])
+	}
+	if _, ok := page["next_cursor"]; ok {
+		t.Errorf("expected no next_cursor on the last page, got %v", page["next_cursor"])
+	}
+}
+
+func TestGetFollows(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		query string
+	}{
+		{"followers", "followers", "ListFollowers"},
+		{"following", "following", "ListFollowing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, fake := fakeConfig(t)
+			handler := cfg.routes()
+
+			userID := uuid.New()
+			followedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			rows := []any{
+				database.ListFollowersRow{ID: uuid.New(), FollowedAt: followedAt},
+				database.ListFollowersRow{ID: uuid.New(), FollowedAt: followedAt.Add(-time.Minute)},
+			}
+			fake.on(tt.query, func(args []driver.Value) ([]any, error) { return rows, nil })
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/users/"+userID.String()+"/"+tt.path+"?limit=1", nil))
+			if rec.Code != 200 {
+				t.Fatalf("expected status 200, got %d (body %q)", rec.Code, rec.Body.String())
+			}
+
+			calls := fake.called(tt.query)
+			if len(calls) != 1 || argUUID(t, calls[0][0]) != userID || calls[0][3] != int64(2) {
+				t.Fatalf("expected one query for %v with a page size of 2, got %v", userID, calls)
+			}
+
+			var page struct {
+				Users      []map[string]any `json:"users"`
+				NextCursor string           `json:"next_cursor"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatalf("unexpected error decoding response: %v", err)
+			}
+			first := rows[0].(database.ListFollowersRow)
+			if len(page.Users) != 1 || page.Users[0]["id"] != first.ID.String() {
+				t.Fatalf("expected only user %v, got %v", first.ID, page.Users)
+			}
+			if _, ok := page.Users[0]["email"]; ok {
+				t.Error("expected follow listings not to expose email addresses")
+			}
+			if want := encodeCursor(pageCursor{CreatedAt: first.FollowedAt, ID: first.ID}); page.NextCursor != want {
+				t.Errorf("expected next cursor %q, got %q", want, page.NextCursor)
+			}
+		})
+	}
+}
+
+func TestFollowUser(t *testing.T) {
+	me, existing, missing := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		method  string
+		target  uuid.UUID
+		status  int
+		queries map[string]int
+	}{
+		{name: "follow", method: "POST", target: existing, status: 204, queries: map[string]int{"GetUserByID": 1, "FollowUser": 1}},
+		{name: "follow unknown user", method: "POST", target: missing, status: 404, queries: map[string]int{"GetUserByID": 1, "FollowUser": 0}},
+		{name: "unfollow", method: "DELETE", target: existing, status: 204, queries: map[string]int{"GetUserByID": 0, "UnfollowUser": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, fake := fakeConfig(t)
+			handler := cfg.routes()
+			token, err := auth.MakeJWTWithClaims(me, auth.Claims{Role: auth.RoleUser}, cfg.JWTKeys, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error creating token: %v", err)
+			}
+
+			fake.on("GetUserByID", func(args []driver.Value) ([]any, error) {
+				if argUUID(t, args[0]) != existing {
+					return nil, nil
+				}
+				return []any{database.User{ID: existing, Email: "them@example.com", Role: auth.RoleUser}}, nil
+			})
+			fake.on("FollowUser", func(args []driver.Value) ([]any, error) { return nil, nil })
+			fake.on("UnfollowUser", func(args []driver.Value) ([]any, error) { return nil, nil })
+
+			req := httptest.NewRequest(tt.method, "/api/users/"+tt.target.String()+"/follow", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d (body %q)", tt.status, rec.Code, rec.Body.String())
+			}
+
+			for query, want := range tt.queries {
+				calls := fake.called(query)
+				if len(calls) != want {
+					t.Fatalf("expected %d %s queries, got %d", want, query, len(calls))
+				}
+				if want > 0 && query != "GetUserByID" {
+					if argUUID(t, calls[0][0]) != me || argUUID(t, calls[0][1]) != tt.target {
+						t.Errorf("expected %s from %v to %v, got %v", query, me, tt.target, calls[0])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxPersonalTokenNameLength = 100
+
+type personalTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Token is only ever set in the response that creates it.
+	Token string `json:"token,omitempty"`
+}
+
+func newPersonalTokenResponse(t database.PersonalAccessToken) personalTokenResponse {
+	resp := personalTokenResponse{
+		ID:        t.ID,
+		Name:      t.Name,
+		Scopes:    t.Scopes,
+		CreatedAt: t.CreatedAt,
+	}
+	if t.ExpiresAt.Valid {
+		resp.ExpiresAt = &t.ExpiresAt.Time
+	}
+	if t.LastUsedAt.Valid {
+		resp.LastUsedAt = &t.LastUsedAt.Time
+	}
+	return resp
+}
+
+// handlerCreatePersonalToken issues a named, scoped API token for scripts
+// and bots. Only the caller's own login can create one, never another
+// delegated token.
+func (cfg *apiConfig) handlerCreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+
+	var errs []fieldError
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		errs = append(errs, fieldError{"name", "required", "Name is required"})
+	} else if len(req.Name) > maxPersonalTokenNameLength {
+		errs = append(errs, fieldError{"name", "too_long", "Name must be at most 100 characters"})
+	}
+	scopes, err := auth.ParseScope(strings.Join(req.Scopes, " "))
+	if err != nil {
+		errs = append(errs, fieldError{"scopes", "invalid", err.Error()})
+	} else if len(scopes) == 0 {
+		errs = append(errs, fieldError{"scopes", "required", "At least one scope is required"})
+	}
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			errs = append(errs, fieldError{"expires_at", "invalid", "Expiry must be in the future"})
+		}
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+	if len(errs) > 0 {
+		respondWithValidationErrors(w, "Invalid token request", errs)
+		return
+	}
+
+	secret, err := auth.MakePersonalToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	pat, err := cfg.queries.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: auth.HashToken(secret),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create token", err)
+		return
+	}
+	resp := newPersonalTokenResponse(pat)
+	resp.Token = secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// handlerListPersonalTokens lists the caller's tokens that haven't been
+// revoked, without their secrets.
+func (cfg *apiConfig) handlerListPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+
+	tokens, err := cfg.queries.ListPersonalAccessTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list tokens", err)
+		return
+	}
+	resp := make([]personalTokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		resp = append(resp, newPersonalTokenResponse(t))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// handlerRevokePersonalToken revokes one of the caller's tokens. A token
+// can also revoke itself through handlerLogout.
+func (cfg *apiConfig) handlerRevokePersonalToken(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid token ID", err)
+		return
+	}
+
+	revoked, err := cfg.queries.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{ID: tokenID, UserID: userID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke token", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Token not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -9,6 +11,7 @@ import (
 	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
 	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // define a struct to use
@@ -25,11 +28,25 @@ type updateUserRequest struct {
 // def a struct for the response without the password?
 // go
 type userResponse struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+}
+
+func newUserResponse(u database.User) userResponse {
+	return userResponse{
+		ID:            u.ID,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+		Email:         u.Email,
+		IsChirpyRed:   u.IsChirpyRed,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerifiedAt.Valid,
+	}
 }
 
 func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request) {
@@ -38,24 +55,26 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 	// decode the request body into the user instance
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Invalid request payload", err)
 		return
 	}
 	if strings.TrimSpace(req.Email) == "" {
-		http.Error(w, "Email is required", http.StatusBadRequest)
+		respondWithValidationErrors(w, "Invalid user", []fieldError{{"email", "required", "Email is required"}})
+		return
+	}
+	if !validEmail(req.Email) {
+		respondWithValidationErrors(w, "Invalid user", []fieldError{{"email", "invalid", "Invalid email address"}})
 		return
 	}
 
-	// make sure password is set and not default
-	if req.Password == "unset" {
-		http.Error(w, "Password is required", http.StatusBadRequest)
+	if !cfg.checkPassword(w, req.Password, req.Email) {
 		return
 	}
 
 	// make the string into a hash
 	hash, err := auth.HashPassword(req.Password)
 	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		respondWithError(w, http.StatusInternalServerError, "Failed to hash password", err)
 		return
 	}
 	newUser, err := cfg.queries.CreateUser(r.Context(), database.CreateUserParams{
@@ -63,56 +82,75 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		HashedPassword: hash,
 	})
 	if err != nil {
-		http.Error(w, "Something went wrong", http.StatusBadRequest)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			respondWithProblem(w, http.StatusConflict, "email_taken", "An account with this email already exists", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
 		return
 	}
-	// create the new user
-	resp := userResponse{
-		ID:        newUser.ID,
-		CreatedAt: newUser.CreatedAt,
-		UpdatedAt: newUser.UpdatedAt,
-		Email:     newUser.Email,
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
+	// new accounts start unverified; a failed send is not fatal since the
+	// user can ask for another email
+	if err := cfg.sendVerificationEmail(r.Context(), newUser.ID, newUser.Email); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", newUser.Email, err)
 	}
+
+	// create the new user
+	respondWithJSON(w, http.StatusCreated, newUserResponse(newUser))
 }
 
 func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request) {
-	gettoken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+	caller := principalFromContext(r.Context())
+	userId, claims := caller.UserID, caller.Claims
+	var req updateUserRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
 		return
 	}
-	userId, err := auth.ValidateJWT(gettoken, cfg.JWTSecret)
+
+	var errs []fieldError
+	switch {
+	case req.Email == "":
+		errs = append(errs, fieldError{"email", "required", "Email is required"})
+	case !validEmail(req.Email):
+		errs = append(errs, fieldError{"email", "invalid", "Invalid email address"})
+	}
+	if req.Password == "" {
+		errs = append(errs, fieldError{"password", "required", "Password is required"})
+	}
+	if len(errs) > 0 {
+		respondWithValidationErrors(w, "Invalid user", errs)
+		return
+	}
+
+	current, err := cfg.queries.GetUserByID(r.Context(), userId)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		respondWithError(w, http.StatusUnauthorized, "Cant Authorize", err)
 		return
 	}
-	// userId is now available for use
-	var req updateUserRequest
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Failed to retrieve request", err)
+	passwordChanged := auth.CheckPasswordHash(req.Password, current.HashedPassword) != nil
+	if passwordChanged && !cfg.checkPassword(w, req.Password, req.Email) {
 		return
 	}
 
-	if req.Email == "" || req.Password == "" {
-		respondWithError(w, http.StatusBadRequest, "Email and Password are required", nil)
+	hashpw, err := auth.HashPassword(req.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
 		return
 	}
 
-	hashpw, err := auth.HashPassword(req.Password)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
 
 	// update user with new hashpw
-	updateuser, err := cfg.queries.UpdateUser(r.Context(), database.UpdateUserParams{
+	updateuser, err := qtx.UpdateUser(r.Context(), database.UpdateUserParams{
 		ID:             userId,
 		Email:          req.Email,
 		HashedPassword: hashpw,
@@ -121,13 +159,46 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusUnauthorized, "Cant Authorize", err)
 		return
 	}
-	// respond with the updated user information
-	resp := userResponse{
-		ID:        updateuser.ID,
-		CreatedAt: updateuser.CreatedAt,
-		UpdatedAt: updateuser.UpdatedAt,
-		Email:     updateuser.Email,
+
+	// a new password invalidates every access token issued so far, every
+	// personal access token and every other session; this session's refresh
+	// token gets the caller a new one
+	if passwordChanged {
+		updateuser.TokenVersion, err = qtx.BumpUserTokenVersion(r.Context(), userId)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+		if err := qtx.RevokeAllPersonalAccessTokens(r.Context(), userId); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+		sessionID, _ := uuid.Parse(claims.SessionID)
+		_, err = qtx.RevokeOtherUserSessions(r.Context(), database.RevokeOtherUserSessionsParams{
+			UserID:   userId,
+			FamilyID: sessionID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+	if passwordChanged {
+		cfg.revocations.setVersion(userId, updateuser.TokenVersion)
 	}
+	// a changed address has to be verified again
+	if updateuser.Email != current.Email {
+		if err := cfg.sendVerificationEmail(r.Context(), updateuser.ID, updateuser.Email); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", updateuser.Email, err)
+		}
+	}
+
+	// respond with the updated user information
+	resp := newUserResponse(updateuser)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -136,3 +207,15 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 	}
 
 }
+
+// handlerGetMe returns the caller's account. OAuth clients need the profile
+// scope.
+func (cfg *apiConfig) handlerGetMe(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+	user, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newUserResponse(user))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+// validEmail reports whether s is a bare address such as a@example.com,
+// without a display name or angle brackets.
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// sendVerificationEmail issues a new verification token for email and mails
+// it to that address. Only the token's hash is stored.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := auth.MakeRandomToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.queries.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Confirm this address by sending the token below to POST /api/users/verify.\n\n%s\n\nIt expires in %s.",
+			token, emailVerificationTTL),
+	})
+}
+
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Failed to retrieve request", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify email", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	vt, err := qtx.GetEmailVerificationTokenForUpdate(r.Context(), auth.HashToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidVerificationToken, "Invalid or expired verification token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify email", err)
+		return
+	}
+	if vt.UsedAt.Valid || !vt.ExpiresAt.After(time.Now()) {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidVerificationToken, "Invalid or expired verification token", nil)
+		return
+	}
+
+	// the token only proves ownership of the address it was sent to, so it is
+	// useless once the user has changed their email
+	user, err := qtx.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+		ID:    vt.UserID,
+		Email: vt.Email,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidVerificationToken, "Invalid or expired verification token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify email", err)
+		return
+	}
+	if err := qtx.UseEmailVerificationTokens(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify email", err)
+		return
+	}
+	// the configured admin account becomes admin once it has proven it owns
+	// the address, if there isn't an admin yet
+	if cfg.adminEmail != "" && user.Email == cfg.adminEmail {
+		promoted, err := qtx.BootstrapAdmin(r.Context(), user.Email)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to verify email", err)
+			return
+		}
+		if promoted > 0 {
+			user.Role = auth.RoleAdmin
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify email", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newUserResponse(user))
+}
+
+// handlerResendVerification mails a fresh verification token to the
+// caller's current address.
+func (cfg *apiConfig) handlerResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromContext(r.Context()).UserID
+
+	user, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidToken, "Invalid token", err)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email is already verified", nil)
+		return
+	}
+	if err := cfg.sendVerificationEmail(r.Context(), user.ID, user.Email); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
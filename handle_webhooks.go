@@ -22,16 +22,16 @@ func (cfg *apiConfig) handler_webhook(w http.ResponseWriter, r *http.Request) {
 	var webhook PolkaWebhook
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&webhook); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Bad request", err)
+		respondWithProblem(w, http.StatusBadRequest, codeInvalidBody, "Bad request", err)
 		return
 	}
 	key, err := auth.GetAPIKey(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid API key", err)
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidAPIKey, "Invalid API key", err)
 		return
 	}
 	if key != cfg.POLKAKey {
-		respondWithError(w, http.StatusUnauthorized, "Invalid API key", nil)
+		respondWithProblem(w, http.StatusUnauthorized, codeInvalidAPIKey, "Invalid API key", nil)
 		return
 	}
 
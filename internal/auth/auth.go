@@ -1,14 +1,14 @@
 package auth
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"strings"
 	"time"
 
-	"fmt"
-
 	"crypto/rand"
+	"crypto/sha256"
 	"net/http"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -29,37 +29,117 @@ func CheckPasswordHash(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	claims := jwt.RegisteredClaims{
+// Roles a user can hold, from least to most privileged.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRank = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAtLeast reports whether have grants everything want does.
+// Unknown roles grant nothing.
+func RoleAtLeast(have, want string) bool {
+	h, ok := roleRank[have]
+	if !ok {
+		return false
+	}
+	return h >= roleRank[want]
+}
+
+// Claims are the JWT claims Chirpy issues.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+	// SessionID is the refresh token family the access token was issued
+	// from. It is empty for tokens not tied to a login session.
+	SessionID string `json:"sid,omitempty"`
+	// TokenVersion is the user's token version when the token was issued.
+	// Bumping the version on the user revokes every older token.
+	TokenVersion int32 `json:"ver"`
+	// ClientID is the OAuth client a token was issued to, and Scope the
+	// space separated scopes it grants. Both are empty for Chirpy's own tokens.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// PersonalTokenID is set on claims resolved from a personal access
+	// token. It is never part of a JWT.
+	PersonalTokenID string `json:"-"`
+}
+
+// Delegated reports whether the claims come from a token with limited
+// scope, one issued to an OAuth client or a personal access token, rather
+// than from a user's own login.
+func (c *Claims) Delegated() bool {
+	return c.ClientID != "" || c.PersonalTokenID != ""
+}
+
+func MakeJWT(userID uuid.UUID, role string, keys *KeySet, expiresIn time.Duration) (string, error) {
+	return MakeJWTWithClaims(userID, Claims{Role: role}, keys, expiresIn)
+}
+
+// MakeJWTWithClaims signs claims for userID. The registered claims (issuer,
+// subject, times and a fresh jti) are always set here and override anything
+// in claims.
+func MakeJWTWithClaims(userID uuid.UUID, claims Claims, keys *KeySet, expiresIn time.Duration) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
 		Issuer:    "chirpy",
 		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)), // ✅ use UTC consistently
 		Subject:   userID.String(),
+		ID:        uuid.NewString(),
 	}
-	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := newToken.SignedString([]byte(tokenSecret))
+	signedToken, err := keys.sign(claims)
 	if err != nil {
 		return "", err
 	}
 	return signedToken, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	var claims jwt.RegisteredClaims
-	token, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (any, error) { // ✅ use any
-		if t.Method.Alg() != jwt.SigningMethodHS256.Alg() { // ✅ safer comparison
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+// ParseJWT validates a token, checking its signature, expiry and revocation,
+// and returns all of its claims.
+// Personal access tokens are resolved to equivalent claims here too, so
+// every handler that reads a bearer token accepts them.
+func ParseJWT(tokenString string, keys *KeySet) (*Claims, error) {
+	return ParseJWTContext(context.Background(), tokenString, keys)
+}
+
+// ParseJWTContext is ParseJWT with a context for the lookups it makes:
+// resolving a personal access token and checking for revocation. Errors
+// wrapping ErrInvalidToken reject the token; any other error means a lookup
+// failed and says nothing about the token.
+func ParseJWTContext(ctx context.Context, tokenString string, keys *KeySet) (*Claims, error) {
+	if strings.HasPrefix(tokenString, PersonalTokenPrefix) {
+		if keys.personal == nil {
+			return nil, ErrInvalidToken
 		}
-		return []byte(tokenSecret), nil
-	})
+		return keys.personal.ResolvePersonalToken(ctx, tokenString)
+	}
+	var claims Claims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, keys.keyFunc)
 	if err != nil || !token.Valid {
-		return uuid.Nil, fmt.Errorf("invalid token")
+		return nil, ErrInvalidToken
 	}
-	id, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.Nil, err
+	if keys.revocations != nil {
+		revoked, err := keys.revocations.IsRevoked(ctx, &claims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
 	}
-	return id, nil
+	return &claims, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -82,13 +162,33 @@ func GetBearerToken(headers http.Header) (string, error) {
 }
 
 func MakeRefreshToken() (string, error) {
-	refreshtoken := make([]byte, 32)
-	_, err := rand.Read(refreshtoken)
+	return MakeRandomToken()
+}
+
+// MakeRandomToken returns 32 random bytes, hex encoded. It is used for every
+// opaque token Chirpy hands out: refresh tokens, email verification tokens
+// and the like.
+func MakeRandomToken() (string, error) {
+	token := make([]byte, 32)
+	_, err := rand.Read(token)
 	if err != nil {
 		return "", err
 	}
-	token := hex.EncodeToString(refreshtoken)
-	return token, nil
+	return hex.EncodeToString(token), nil
+}
+
+// HashRefreshToken returns the digest a refresh token is stored and looked
+// up by. Refresh tokens are 32 random bytes, so an unsalted SHA-256 is
+// enough to make a database dump useless without slowing down lookups.
+func HashRefreshToken(token string) string {
+	return HashToken(token)
+}
+
+// HashToken returns the hex encoded SHA-256 of a token from MakeRandomToken.
+// Only this digest is ever stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func GetAPIKey(headers http.Header) (string, error) {
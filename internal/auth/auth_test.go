@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"testing"
 	"time"
@@ -8,47 +10,213 @@ import (
 	"github.com/google/uuid"
 )
 
-func TestMakeAndValidateJWT(t *testing.T) {
-	secret := "super-secret-key"
+func TestMakeAndParseJWT(t *testing.T) {
+	keys := NewHMACKeySet("super-secret-key")
 	userID := uuid.New()
 
 	// --- Case 1: Valid token ---
-	token, err := MakeJWT(userID, secret, time.Minute)
+	token, err := MakeJWT(userID, RoleUser, keys, time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error creating token: %v", err)
 	}
 
-	parsedID, err := ValidateJWT(token, secret)
+	claims, err := ParseJWT(token, keys)
 	if err != nil {
-		t.Fatalf("unexpected error validating token: %v", err)
+		t.Fatalf("unexpected error parsing token: %v", err)
 	}
-	if parsedID != userID {
-		t.Errorf("expected userID %v, got %v", userID, parsedID)
+	if claims.Subject != userID.String() {
+		t.Errorf("expected subject %v, got %v", userID, claims.Subject)
 	}
 
 	// --- Case 2: Expired token ---
-	expiredToken, err := MakeJWT(userID, secret, -time.Minute)
+	expiredToken, err := MakeJWT(userID, RoleUser, keys, -time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error creating expired token: %v", err)
 	}
 
-	_, err = ValidateJWT(expiredToken, secret)
+	_, err = ParseJWT(expiredToken, keys)
 	if err == nil {
 		t.Error("expected error for expired token, got nil")
 	}
 
 	// --- Case 3: Wrong secret ---
-	token2, err := MakeJWT(userID, secret, time.Minute)
+	token2, err := MakeJWT(userID, RoleUser, keys, time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error creating token: %v", err)
 	}
 
-	_, err = ValidateJWT(token2, "wrong-secret")
+	_, err = ParseJWT(token2, NewHMACKeySet("wrong-secret"))
 	if err == nil {
 		t.Error("expected error for token signed with wrong secret, got nil")
 	}
 }
 
+func TestJWTRoleClaim(t *testing.T) {
+	keys := NewHMACKeySet("super-secret-key")
+	userID := uuid.New()
+
+	token, err := MakeJWT(userID, RoleModerator, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+
+	claims, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("unexpected error parsing token: %v", err)
+	}
+	if claims.Role != RoleModerator {
+		t.Errorf("expected role %q, got %q", RoleModerator, claims.Role)
+	}
+	if claims.Subject != userID.String() {
+		t.Errorf("expected subject %v, got %v", userID, claims.Subject)
+	}
+}
+
+func TestJWTSessionClaim(t *testing.T) {
+	keys := NewHMACKeySet("super-secret-key")
+	userID := uuid.New()
+	sessionID := uuid.New().String()
+
+	token, err := MakeJWTWithClaims(userID, Claims{Role: RoleUser, SessionID: sessionID}, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+
+	claims, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("unexpected error parsing token: %v", err)
+	}
+	if claims.SessionID != sessionID {
+		t.Errorf("expected session %q, got %q", sessionID, claims.SessionID)
+	}
+	if claims.Subject != userID.String() || claims.Issuer != "chirpy" {
+		t.Errorf("registered claims not set: %+v", claims.RegisteredClaims)
+	}
+}
+
+type denyJTI string
+
+func (d denyJTI) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	return claims.ID == string(d), nil
+}
+
+func TestRevocationChecker(t *testing.T) {
+	keys := NewHMACKeySet("super-secret-key")
+	userID := uuid.New()
+
+	revoked, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	kept, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	claims, err := ParseJWT(revoked, keys)
+	if err != nil {
+		t.Fatalf("unexpected error parsing token: %v", err)
+	}
+	if claims.ID == "" {
+		t.Fatal("expected token to carry a jti")
+	}
+
+	keys.SetRevocationChecker(denyJTI(claims.ID))
+	if _, err := ParseJWT(revoked, keys); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected ErrTokenRevoked, got %v", err)
+	}
+	if _, err := ParseJWT(kept, keys); err != nil {
+		t.Errorf("unexpected error parsing token: %v", err)
+	}
+}
+
+func TestParseJWTErrors(t *testing.T) {
+	keys := NewHMACKeySet("super-secret-key")
+	userID := uuid.New()
+	expired, err := MakeJWT(userID, RoleUser, keys, -time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	valid, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		keys  *KeySet
+	}{
+		{"garbage", "not-a-jwt", keys},
+		{"expired", expired, keys},
+		{"wrong key", valid, NewHMACKeySet("wrong-secret")},
+		{"personal token without resolver", PersonalTokenPrefix + "abc", keys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJWT(tt.token, tt.keys); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+
+	if !errors.Is(ErrTokenRevoked, ErrInvalidToken) {
+		t.Error("expected ErrTokenRevoked to wrap ErrInvalidToken")
+	}
+	lookupErr := errors.New("database is down")
+	keys.SetRevocationChecker(failingChecker{lookupErr})
+	if _, err := ParseJWT(valid, keys); !errors.Is(err, lookupErr) || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected the lookup error, not ErrInvalidToken, got %v", err)
+	}
+}
+
+// failingChecker can't tell whether any token is revoked.
+type failingChecker struct{ err error }
+
+func (f failingChecker) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	return false, f.err
+}
+
+func TestRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		have, want string
+		expected   bool
+	}{
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleUser, RoleModerator, false},
+		{RoleModerator, RoleAdmin, false},
+		{"", RoleUser, false},
+		{"superuser", RoleUser, false},
+	}
+	for _, tt := range tests {
+		if got := RoleAtLeast(tt.have, tt.want); got != tt.expected {
+			t.Errorf("RoleAtLeast(%q, %q) = %v, want %v", tt.have, tt.want, got, tt.expected)
+		}
+	}
+}
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("unexpected error creating refresh token: %v", err)
+	}
+
+	hash := HashRefreshToken(token)
+	if hash == token {
+		t.Error("expected hash to differ from token")
+	}
+	if len(hash) != 64 {
+		t.Errorf("expected 64 hex characters, got %d", len(hash))
+	}
+	if HashRefreshToken(token) != hash {
+		t.Error("expected hashing to be deterministic")
+	}
+	// must match encode(sha256(...), 'hex') used by the conversion migration
+	if got := HashRefreshToken("abc"); got != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("unexpected digest %q", got)
+	}
+}
+
 func TestGetBearerToken(t *testing.T) {
 	tests := []struct {
 		name      string
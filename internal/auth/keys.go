@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSABits = 2048
+
+// jwtKey is one key a token can be signed or verified with.
+type jwtKey struct {
+	kid    string
+	method jwt.SigningMethod
+	// private is nil for keys that may only verify.
+	private any
+	public  any
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted from. During a rotation the directory holds the new
+// private key plus the old one (or just its public half) until every token
+// the old key signed has expired.
+type KeySet struct {
+	signer      *jwtKey
+	keys        map[string]*jwtKey
+	revocations RevocationChecker
+	personal    PersonalTokenResolver
+}
+
+// ErrInvalidToken is wrapped by every error that means the token itself is
+// no good, as opposed to a failure while checking it.
+var ErrInvalidToken = errors.New("invalid token")
+
+var ErrTokenRevoked = fmt.Errorf("%w: token has been revoked", ErrInvalidToken)
+
+// RevocationChecker decides whether a correctly signed, unexpired token has
+// since been revoked, for example by logging out or changing the password.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *Claims) (bool, error)
+}
+
+// SetRevocationChecker makes ParseJWT consult rc for every token that
+// passes signature and expiry checks.
+func (ks *KeySet) SetRevocationChecker(rc RevocationChecker) {
+	ks.revocations = rc
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with HS256 using
+// secret. Tokens carry no kid. It is what Chirpy uses when no key directory
+// is configured.
+func NewHMACKeySet(secret string) *KeySet {
+	k := &jwtKey{
+		method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+	return &KeySet{signer: k, keys: map[string]*jwtKey{"": k}}
+}
+
+// LoadKeySet reads every *.pem file in dir. The file name without the
+// extension is the kid. Files may hold an RSA or Ed25519 private key
+// (PKCS#8, or PKCS#1 for RSA) or a public key (PKIX) that is only used for
+// verification. New tokens are signed with signingKID, or when it is empty
+// with the private key whose file name sorts last, so naming keys by date
+// makes the newest one sign.
+func LoadKeySet(dir, signingKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	ks := &KeySet{keys: map[string]*jwtKey{}}
+	var lastPrivate *jwtKey
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		k, err := loadKey(path, kid)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", path, err)
+		}
+		ks.keys[kid] = k
+		if k.private != nil {
+			lastPrivate = k
+		}
+	}
+
+	if signingKID == "" {
+		ks.signer = lastPrivate
+	} else if k, ok := ks.keys[signingKID]; ok && k.private != nil {
+		ks.signer = k
+	}
+	if ks.signer == nil {
+		return nil, fmt.Errorf("no private signing key found in %s", dir)
+	}
+	return ks, nil
+}
+
+func loadKey(path, kid string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		return &jwtKey{kid: kid, method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		return &jwtKey{kid: kid, method: jwt.SigningMethodRS256, public: k}, nil
+	case ed25519.PrivateKey:
+		return &jwtKey{kid: kid, method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &jwtKey{kid: kid, method: jwt.SigningMethodEdDSA, public: k}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", key)
+}
+
+// sign signs claims with the current signing key.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signer.method, claims)
+	if ks.signer.kid != "" {
+		token.Header["kid"] = ks.signer.kid
+	}
+	return token.SignedString(ks.signer.private)
+}
+
+// keyFunc picks the verification key named by the token's kid and refuses
+// tokens whose algorithm does not match that key, so an RSA public key can
+// never be used as an HMAC secret.
+func (ks *KeySet) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return k.public, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every asymmetric key in the set, sorted
+// by kid. HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		jwk := JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
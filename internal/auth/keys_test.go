@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+}
+
+func writePrivateKey(t *testing.T, dir, name string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshalling key: %v", err)
+	}
+	writePEM(t, dir, name, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, dir, name string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("marshalling key: %v", err)
+	}
+	writePEM(t, dir, name, "PUBLIC KEY", der)
+}
+
+func TestLoadKeySetSignsWithNewestKey(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePrivateKey(t, dir, "2026-01.pem", rsaKey)
+	writePrivateKey(t, dir, "2026-02.pem", edKey)
+
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("unexpected error loading keys: %v", err)
+	}
+
+	userID := uuid.New()
+	token, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header["kid"] != "2026-02" || parsed.Method.Alg() != "EdDSA" {
+		t.Errorf("expected EdDSA token with kid 2026-02, got %v", parsed.Header)
+	}
+
+	claims, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("unexpected error parsing token: %v", err)
+	}
+	if claims.Subject != userID.String() {
+		t.Errorf("expected subject %v, got %v", userID, claims.Subject)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a token signed before the rotation
+	oldDir := t.TempDir()
+	writePrivateKey(t, oldDir, "old.pem", oldKey)
+	oldKeys, err := LoadKeySet(oldDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := MakeJWT(uuid.New(), RoleUser, oldKeys, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// after the rotation only the public half of the old key is kept
+	newDir := t.TempDir()
+	writePublicKey(t, newDir, "old.pem", &oldKey.PublicKey)
+	writePrivateKey(t, newDir, "new.pem", newKey)
+	newKeys, err := LoadKeySet(newDir, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseJWT(token, newKeys); err != nil {
+		t.Errorf("expected token from retired key to verify, got %v", err)
+	}
+
+	// once the old key is gone its tokens are rejected
+	if err := os.Remove(filepath.Join(newDir, "old.pem")); err != nil {
+		t.Fatal(err)
+	}
+	newKeys, err = LoadKeySet(newDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseJWT(token, newKeys); err == nil {
+		t.Error("expected error for token from removed key, got nil")
+	}
+}
+
+func TestLoadKeySetRequiresPrivateKey(t *testing.T) {
+	dir := t.TempDir()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePublicKey(t, dir, "only-public.pem", pub)
+
+	if _, err := LoadKeySet(dir, ""); err == nil {
+		t.Error("expected error without a private key, got nil")
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePrivateKey(t, dir, "rsa.pem", rsaKey)
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// HS256 keyed with the published public key must not verify
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	forged.Header["kid"] = "rsa"
+	token, err := forged.SignedString(pubDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseJWT(token, keys); err == nil {
+		t.Error("expected error for HS256 token with RSA kid, got nil")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePrivateKey(t, dir, "a-rsa.pem", rsaKey)
+	writePrivateKey(t, dir, "b-ed.pem", edKey)
+
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := keys.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(set.Keys))
+	}
+	if k := set.Keys[0]; k.Kid != "a-rsa" || k.Kty != "RSA" || k.Alg != "RS256" || k.E != "AQAB" || k.N == "" {
+		t.Errorf("unexpected RSA JWK %+v", k)
+	}
+	if k := set.Keys[1]; k.Kid != "b-ed" || k.Kty != "OKP" || k.Crv != "Ed25519" || k.Alg != "EdDSA" || len(k.X) != 43 {
+		t.Errorf("unexpected Ed25519 JWK %+v", k)
+	}
+
+	if got := NewHMACKeySet("secret").JWKS(); len(got.Keys) != 0 {
+		t.Errorf("expected HMAC secret not to be published, got %+v", got.Keys)
+	}
+}
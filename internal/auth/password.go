@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPasswordBytes is the most bcrypt looks at. Longer passwords are
+// refused rather than silently truncated.
+const MaxPasswordBytes = 72
+
+// Codes identifying why a password was refused.
+const (
+	PasswordRequired     = "required"
+	PasswordTooShort     = "too_short"
+	PasswordTooLong      = "too_long"
+	PasswordMatchesEmail = "matches_email"
+	PasswordBreached     = "breached"
+)
+
+// PasswordViolation is one reason a password was refused.
+type PasswordViolation struct {
+	Code    string
+	Message string
+}
+
+// PasswordPolicy decides which passwords are acceptable.
+type PasswordPolicy struct {
+	// MinLength is counted in characters, not bytes.
+	MinLength int
+	// Breached is consulted when set.
+	Breached *BreachedList
+}
+
+// Check returns every way password breaks the policy, or nil if it is
+// acceptable. email is the address of the account it is for.
+func (p PasswordPolicy) Check(password, email string) []PasswordViolation {
+	if password == "" {
+		return []PasswordViolation{{PasswordRequired, "Password is required"}}
+	}
+
+	var v []PasswordViolation
+	if utf8.RuneCountInString(password) < p.MinLength {
+		v = append(v, PasswordViolation{PasswordTooShort, fmt.Sprintf("Password must be at least %d characters", p.MinLength)})
+	}
+	if len(password) > MaxPasswordBytes {
+		v = append(v, PasswordViolation{PasswordTooLong, fmt.Sprintf("Password must be at most %d bytes", MaxPasswordBytes)})
+	}
+	if matchesEmail(password, email) {
+		v = append(v, PasswordViolation{PasswordMatchesEmail, "Password must not be your email address"})
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		v = append(v, PasswordViolation{PasswordBreached, "Password has appeared in a data breach"})
+	}
+	return v
+}
+
+func matchesEmail(password, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	pw := strings.ToLower(strings.TrimSpace(password))
+	local, _, _ := strings.Cut(email, "@")
+	return pw == email || pw == local
+}
+
+// hashPrefixLen is the length of the SHA-1 prefix a k-anonymity range
+// lookup is keyed by.
+const hashPrefixLen = 5
+
+// BreachedList is a local copy of known breached password hashes. It is
+// indexed the way the k-anonymity range API is queried, by the first five
+// hex digits of the SHA-1, so it can be swapped for a remote lookup without
+// ever handling more than a prefix of the hash.
+type BreachedList struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedList reads a file of upper or lower case SHA-1 hashes, one per
+// line, optionally followed by ":count" as in the Pwned Passwords downloads.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &BreachedList{ranges: map[string]map[string]struct{}{}}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if hash == "" {
+			continue
+		}
+		if len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("%s:%d: not a SHA-1 hash", path, line)
+		}
+		hash = strings.ToUpper(hash)
+		prefix, suffix := hash[:hashPrefixLen], hash[hashPrefixLen:]
+		if list.ranges[prefix] == nil {
+			list.ranges[prefix] = map[string]struct{}{}
+		}
+		list.ranges[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Contains reports whether password is in the list.
+func (b *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, ok := b.ranges[hash[:hashPrefixLen]][hash[hashPrefixLen:]]
+	return ok
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func violationCodes(v []PasswordViolation) []string {
+	var codes []string
+	for _, x := range v {
+		codes = append(codes, x.Code)
+	}
+	return codes
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	p := PasswordPolicy{MinLength: 8}
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		want     []string
+	}{
+		{"acceptable", "correct horse battery", "a@example.com", nil},
+		{"empty", "", "a@example.com", []string{PasswordRequired}},
+		{"too short", "abc", "a@example.com", []string{PasswordTooShort}},
+		{"length counts characters", "ééééééé", "a@example.com", []string{PasswordTooShort}},
+		{"over bcrypt limit", strings.Repeat("a", 73), "a@example.com", []string{PasswordTooLong}},
+		{"exactly bcrypt limit", strings.Repeat("a", 72), "a@example.com", nil},
+		{"email", "Walt@Example.com", "walt@example.com", []string{PasswordMatchesEmail}},
+		{"email local part", "walterwhite", "walterwhite@example.com", []string{PasswordMatchesEmail}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violationCodes(p.Check(tt.password, tt.email))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreachedList(t *testing.T) {
+	// SHA-1 of "password" and "123456", in the Pwned Passwords format
+	content := "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n" +
+		"7c4a8d09ca3762af61e59520943dc26494f8941b\n\n"
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadBreachedList(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading list: %v", err)
+	}
+	for _, pw := range []string{"password", "123456"} {
+		if !list.Contains(pw) {
+			t.Errorf("expected %q to be breached", pw)
+		}
+	}
+	if list.Contains("correct horse battery") {
+		t.Error("expected unlisted password not to be breached")
+	}
+
+	p := PasswordPolicy{MinLength: 6, Breached: list}
+	if got := violationCodes(p.Check("password", "a@example.com")); !reflect.DeepEqual(got, []string{PasswordBreached}) {
+		t.Errorf("expected breached violation, got %v", got)
+	}
+}
+
+func TestLoadBreachedListRejectsGarbage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte("not-a-hash\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBreachedList(path); err == nil {
+		t.Error("expected error for malformed line, got nil")
+	}
+}
@@ -0,0 +1,32 @@
+package auth
+
+import "context"
+
+// PersonalTokenPrefix starts every personal access token, which tells them
+// apart from JWTs in an Authorization header and makes leaked tokens easy
+// to scan for.
+const PersonalTokenPrefix = "chirpy_pat_"
+
+// PersonalTokenResolver looks up a personal access token and returns claims
+// standing in for it: the owner as subject, its scopes, and its ID in
+// PersonalTokenID. Unknown, expired and revoked tokens are an error
+// wrapping ErrInvalidToken.
+type PersonalTokenResolver interface {
+	ResolvePersonalToken(ctx context.Context, token string) (*Claims, error)
+}
+
+// SetPersonalTokenResolver makes ParseJWT and friends accept personal
+// access tokens, resolved through pr.
+func (ks *KeySet) SetPersonalTokenResolver(pr PersonalTokenResolver) {
+	ks.personal = pr
+}
+
+// MakePersonalToken returns a new personal access token. Like every opaque
+// token it is stored only as its HashToken digest.
+func MakePersonalToken() (string, error) {
+	token, err := MakeRandomToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalTokenPrefix + token, nil
+}
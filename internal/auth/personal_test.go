@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type fakePersonalTokens map[string]*Claims
+
+func (f fakePersonalTokens) ResolvePersonalToken(ctx context.Context, token string) (*Claims, error) {
+	c, ok := f[token]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return c, nil
+}
+
+func TestPersonalTokens(t *testing.T) {
+	keys := NewHMACKeySet("super-secret-key")
+	userID := uuid.New()
+
+	token, err := MakePersonalToken()
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	if !strings.HasPrefix(token, PersonalTokenPrefix) {
+		t.Fatalf("token %q lacks prefix %q", token, PersonalTokenPrefix)
+	}
+
+	// without a resolver personal tokens are rejected outright
+	if _, err := ParseJWT(token, keys); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken without a resolver, got %v", err)
+	}
+
+	claims := &Claims{Scope: ScopeChirpsRead, PersonalTokenID: uuid.NewString()}
+	claims.Subject = userID.String()
+	keys.SetPersonalTokenResolver(fakePersonalTokens{token: claims})
+
+	got, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("unexpected error parsing token: %v", err)
+	}
+	if got.Subject != userID.String() || !got.Delegated() {
+		t.Errorf("expected delegated claims for %v, got %+v", userID, got)
+	}
+	if !got.HasScope(ScopeChirpsRead) || got.HasScope(ScopeChirpsWrite) {
+		t.Errorf("expected only the %s scope, got %q", ScopeChirpsRead, got.Scope)
+	}
+	if _, err := ParseJWT(PersonalTokenPrefix+"unknown", keys); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for unknown token, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scopes a third-party client can be granted.
+const (
+	ScopeChirpsRead  = "chirps:read"
+	ScopeChirpsWrite = "chirps:write"
+	ScopeProfile     = "profile"
+)
+
+var scopeDescriptions = map[string]string{
+	ScopeChirpsRead:  "Read chirps, your timeline and mentions",
+	ScopeChirpsWrite: "Post, edit and delete chirps as you",
+	ScopeProfile:     "See your email address and account details",
+}
+
+// ErrInsufficientScope is returned for a valid token that does not grant
+// what the request needs.
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// ScopeDescription returns the consent text for scope.
+func ScopeDescription(scope string) (string, bool) {
+	d, ok := scopeDescriptions[scope]
+	return d, ok
+}
+
+// ParseScope splits a space separated scope parameter (RFC 6749 section
+// 3.3), dropping duplicates. Unknown scopes are an error.
+func ParseScope(s string) ([]string, error) {
+	var scopes []string
+	for _, scope := range strings.Fields(s) {
+		if _, ok := scopeDescriptions[scope]; !ok {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// HasScope reports whether the token grants scope. Tokens Chirpy issues to
+// its own users grant everything; delegated tokens grant only the scopes
+// the user chose for them.
+func (c *Claims) HasScope(scope string) bool {
+	if !c.Delegated() {
+		return true
+	}
+	return slices.Contains(strings.Fields(c.Scope), scope)
+}
+
+// CheckPKCE reports whether verifier matches an S256 code challenge
+// (RFC 7636 section 4.6).
+func CheckPKCE(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScope(t *testing.T) {
+	got, err := ParseScope("chirps:read  profile chirps:read")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{ScopeChirpsRead, ScopeProfile}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseScope = %v, want %v", got, want)
+	}
+	if _, err := ParseScope("chirps:read admin"); err == nil {
+		t.Error("expected error for unknown scope, got nil")
+	}
+}
+
+func TestClaimsHasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims Claims
+		scope  string
+		want   bool
+	}{
+		{"client granted", Claims{ClientID: "client", Scope: ScopeChirpsRead}, ScopeChirpsRead, true},
+		{"client not granted", Claims{ClientID: "client", Scope: ScopeChirpsRead}, ScopeChirpsWrite, false},
+		{"personal granted", Claims{PersonalTokenID: "pat", Scope: "profile chirps:write"}, ScopeChirpsWrite, true},
+		{"personal without scopes", Claims{PersonalTokenID: "pat"}, ScopeProfile, false},
+		{"first party grants everything", Claims{Role: RoleUser}, ScopeChirpsWrite, true},
+	}
+	for _, tt := range tests {
+		if got := tt.claims.HasScope(tt.scope); got != tt.want {
+			t.Errorf("%s: HasScope(%q) = %v, want %v", tt.name, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPKCE(t *testing.T) {
+	// RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	if !CheckPKCE(verifier, challenge) {
+		t.Error("expected RFC 7636 example to verify")
+	}
+	if CheckPKCE("wrong", challenge) {
+		t.Error("expected wrong verifier to fail")
+	}
+}
@@ -7,24 +7,86 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countChirpReplies = `-- name: CountChirpReplies :one
+SELECT COUNT(*)
+ FROM chirps
+ WHERE parent_chirp_id = $1::uuid
+`
+
+func (q *Queries) CountChirpReplies(ctx context.Context, chirpID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpReplies, chirpID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countRepliesForChirps = `-- name: CountRepliesForChirps :many
+SELECT parent_chirp_id, COUNT(*) AS reply_count
+ FROM chirps
+ WHERE parent_chirp_id = ANY($1::uuid[])
+   AND deleted_at IS NULL
+   AND hidden_at IS NULL
+ GROUP BY parent_chirp_id
+`
+
+type CountRepliesForChirpsRow struct {
+	ParentChirpID uuid.NullUUID
+	ReplyCount    int64
+}
+
+func (q *Queries) CountRepliesForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]CountRepliesForChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countRepliesForChirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRepliesForChirpsRow
+	for rows.Next() {
+		var i CountRepliesForChirpsRow
+		if err := rows.Scan(
+			&i.ParentChirpID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, body, user_id)
- VALUES ($1, $2, $3)
- RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO chirps (id, body, user_id, parent_chirp_id)
+ VALUES ($1, $2, $3, $4)
+ RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
 `
 
 type CreateChirpParams struct {
-	ID     uuid.UUID
-	Body   string
-	UserID uuid.UUID
+	ID            uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.ID, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.ID,
+		arg.Body,
+		arg.UserID,
+		arg.ParentChirpID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -32,13 +94,18 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
  FROM chirps
+ WHERE deleted_at IS NULL
+   AND hidden_at IS NULL
  ORDER BY created_at ASC, id ASC
 `
 
@@ -57,6 +124,9 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -72,7 +142,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
  FROM chirps
  WHERE id = $1
 `
@@ -86,14 +156,139 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, parent_chirp_id, depth) AS (
+    SELECT c.id, c.parent_chirp_id, 0
+     FROM chirps c
+     WHERE c.id = $1::uuid
+    UNION ALL
+    SELECT p.id, p.parent_chirp_id, a.depth + 1
+     FROM chirps p
+     JOIN ancestors a ON p.id = a.parent_chirp_id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at
+ FROM chirps
+ JOIN ancestors ON ancestors.id = chirps.id
+ WHERE ancestors.depth > 0
+ ORDER BY ancestors.depth DESC
+`
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, chirpID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants (id) AS (
+    SELECT c.id
+     FROM chirps c
+     WHERE c.parent_chirp_id = $1::uuid
+    UNION ALL
+    SELECT c.id
+     FROM chirps c
+     JOIN descendants d ON c.parent_chirp_id = d.id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at
+ FROM chirps
+ JOIN descendants ON descendants.id = chirps.id
+ ORDER BY chirps.created_at ASC, chirps.id ASC
+`
+
+func (q *Queries) GetChirpDescendants(ctx context.Context, chirpID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
+ FROM chirps
+ WHERE id = $1
+ FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
  FROM chirps
  WHERE user_id = $1
+   AND deleted_at IS NULL
+   AND hidden_at IS NULL
  ORDER BY created_at ASC, id ASC
 `
 
@@ -112,6 +307,193 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at
+ FROM chirps
+ JOIN follows ON follows.followee_id = chirps.user_id
+ WHERE follows.follower_id = $1::uuid
+   AND chirps.deleted_at IS NULL
+   AND chirps.hidden_at IS NULL
+   AND ($2::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid))
+ ORDER BY chirps.created_at DESC, chirps.id DESC
+ LIMIT $4
+`
+
+type GetTimelineParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hideChirp = `-- name: HideChirp :execrows
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1
+  AND hidden_at IS NULL
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, hideChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
+ FROM chirps
+ WHERE deleted_at IS NULL
+   AND hidden_at IS NULL
+   AND ($1::uuid IS NULL OR user_id = $1::uuid)
+   AND ($2::timestamp IS NULL
+        OR (created_at, id) > ($2::timestamp, $3::uuid))
+ ORDER BY created_at ASC, id ASC
+ LIMIT $4
+`
+
+type ListChirpsAscParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
+ FROM chirps
+ WHERE deleted_at IS NULL
+   AND hidden_at IS NULL
+   AND ($1::uuid IS NULL OR user_id = $1::uuid)
+   AND ($2::timestamp IS NULL
+        OR (created_at, id) < ($2::timestamp, $3::uuid))
+ ORDER BY created_at DESC, id DESC
+ LIMIT $4
+`
+
+type ListChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -135,3 +517,141 @@ func (q *Queries) RemoveChirp(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, removeChirp, id)
 	return err
 }
+
+const restoreChirp = `-- name: RestoreChirp :execrows
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1
+  AND hidden_at IS NOT NULL
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at,
+       ts_rank(to_tsvector('english', chirps.body), query)::real AS rank,
+       ts_headline('english',
+                   replace(replace(replace(replace(replace(chirps.body,
+                       '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+                   query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')::text AS snippet
+ FROM chirps, websearch_to_tsquery('english', $1::text) AS query
+ WHERE to_tsvector('english', chirps.body) @@ query
+   AND chirps.deleted_at IS NULL
+   AND chirps.hidden_at IS NULL
+   AND ($2::uuid IS NULL OR chirps.user_id = $2::uuid)
+   AND ($3::timestamp IS NULL OR chirps.created_at >= $3::timestamp)
+   AND ($4::timestamp IS NULL OR chirps.created_at < $4::timestamp)
+ ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+ LIMIT $5 OFFSET $6
+`
+
+type SearchChirpsRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	DeletedAt     sql.NullTime
+	HiddenAt      sql.NullTime
+	Rank          float32
+	Snippet       string
+}
+
+type SearchChirpsParams struct {
+	Query      string
+	AuthorID   uuid.NullUUID
+	Since      sql.NullTime
+	Until      sql.NullTime
+	PageSize   int32
+	PageOffset int32
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.Since,
+		arg.Until,
+		arg.PageSize,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
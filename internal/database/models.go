@@ -12,27 +12,232 @@ import (
 )
 
 type Chirp struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	DeletedAt     sql.NullTime
+	HiddenAt      sql.NullTime
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpReport struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ChirpID    uuid.UUID
+	Reason     string
+	ReporterID uuid.NullUUID
+	Status     string
+	ResolvedAt sql.NullTime
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginAttempt struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+	Email     string
+	UserID    uuid.NullUUID
+	Ip        string
+	Outcome   string
+}
+
+type MfaChallenge struct {
+	TokenHash        string
+	CreatedAt        time.Time
+	UserID           uuid.UUID
+	ExpiresAt        time.Time
+	AccessTtlSeconds int32
+	Attempts         int32
+	UsedAt           sql.NullTime
+}
+
+type MfaRecoveryCode struct {
 	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
 }
 
-type RefreshToken struct {
-	Token     string
+type ModerationAction struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ActorID   uuid.NullUUID
+	Action    string
+	ChirpID   uuid.NullUUID
+	UserID    uuid.NullUUID
+	ReportID  uuid.NullUUID
+	Note      string
+}
+
+type ModerationWord struct {
+	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Word      string
+	Action    string
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash        string
+	CreatedAt       time.Time
+	ClientID        uuid.UUID
+	UserID          uuid.UUID
+	RedirectUri     string
+	Scopes          []string
+	CodeChallenge   sql.NullString
+	ExpiresAt       time.Time
+	UsedAt          sql.NullTime
+	RedirectUriSent bool
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	Scopes       []string
+}
+
+type OidcLoginState struct {
+	StateHash    string
+	CreatedAt    time.Time
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type PasswordResetRequest struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Email     string
+	Ip        string
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	CreatedAt time.Time
 	UserID    uuid.UUID
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
 }
 
-type User struct {
-	ID             uuid.UUID
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type RefreshToken struct {
+	TokenHash      string
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	UserID         uuid.UUID
+	ExpiresAt      time.Time
+	RevokedAt      sql.NullTime
+	FamilyID       uuid.UUID
+	ReplacedByHash sql.NullString
+	LastUsedAt     sql.NullTime
+	UserAgent      string
+	Ip             string
+}
+
+type RevokedAccessToken struct {
+	Jti       string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
+type RevokedSession struct {
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
+type SecurityEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.NullUUID
+	Event     string
+	Ip        string
+	Detail    string
+}
+
+type User struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Email             string
+	HashedPassword    string
+	IsChirpyRed       bool
+	SuspendedAt       sql.NullTime
+	Role              string
+	TokenVersion      int32
+	EmailVerifiedAt   sql.NullTime
+	TotpSecret        sql.NullString
+	TotpEnabledAt     sql.NullTime
+	TotpLastCounter   int64
+	FailedLoginCount  int32
+	LastFailedLoginAt sql.NullTime
+	LockedUntil       sql.NullTime
+}
+
+type UserIdentity struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Provider  string
+	Subject   string
+	Email     string
 }
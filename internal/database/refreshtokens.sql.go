@@ -7,56 +7,95 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, family_id, last_used_at, user_agent, ip)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by_hash, last_used_at, user_agent, ip
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	ExpiresAt time.Time
+	TokenHash  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	FamilyID   uuid.UUID
+	LastUsedAt sql.NullTime
+	UserAgent  string
+	Ip         string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
 	row := q.db.QueryRowContext(ctx, createRefreshToken,
-		arg.Token,
+		arg.TokenHash,
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.LastUsedAt,
+		arg.UserAgent,
+		arg.Ip,
 	)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
+		&i.TokenHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedByHash,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.Ip,
+	)
+	return i, err
+}
+
+const getRefreshTokenForUpdate = `-- name: GetRefreshTokenForUpdate :one
+SELECT token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by_hash, last_used_at, user_agent, ip FROM refresh_tokens
+WHERE token_hash = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRefreshTokenForUpdate(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenForUpdate, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedByHash,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.Ip,
 	)
 	return i, err
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.suspended_at, users.role, users.token_version, users.email_verified_at, users.totp_secret, users.totp_enabled_at, users.totp_last_counter, users.failed_login_count, users.last_failed_login_at, users.locked_until
 FROM users
 JOIN refresh_tokens ON refresh_tokens.user_id = users.id
-WHERE refresh_tokens.token = $1
+WHERE refresh_tokens.token_hash = $1
   AND refresh_tokens.revoked_at IS NULL
   AND refresh_tokens.expires_at > NOW()
 `
 
-func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
+func (q *Queries) GetUserFromRefreshToken(ctx context.Context, tokenHash string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, tokenHash)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -65,18 +104,169 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
+const listUserSessions = `-- name: ListUserSessions :many
+SELECT refresh_tokens.family_id,
+       (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = refresh_tokens.family_id)::timestamp AS started_at,
+       refresh_tokens.last_used_at,
+       refresh_tokens.user_agent,
+       refresh_tokens.ip,
+       refresh_tokens.expires_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+  AND refresh_tokens.expires_at > NOW()
+ORDER BY COALESCE(refresh_tokens.last_used_at, refresh_tokens.created_at) DESC
+`
+
+type ListUserSessionsRow struct {
+	FamilyID   uuid.UUID
+	StartedAt  time.Time
+	LastUsedAt sql.NullTime
+	UserAgent  string
+	Ip         string
+	ExpiresAt  time.Time
+}
+
+func (q *Queries) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]ListUserSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserSessionsRow
+	for rows.Next() {
+		var i ListUserSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.StartedAt,
+			&i.LastUsedAt,
+			&i.UserAgent,
+			&i.Ip,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markRefreshTokenRotated = `-- name: MarkRefreshTokenRotated :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW(),
+    replaced_by_hash = $2
+WHERE token_hash = $1
+`
+
+type MarkRefreshTokenRotatedParams struct {
+	TokenHash      string
+	ReplacedByHash sql.NullString
+}
+
+func (q *Queries) MarkRefreshTokenRotated(ctx context.Context, arg MarkRefreshTokenRotatedParams) error {
+	_, err := q.db.ExecContext(ctx, markRefreshTokenRotated, arg.TokenHash, arg.ReplacedByHash)
+	return err
+}
+
+const revokeAllUserRefreshTokens = `-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllUserRefreshTokens, userID)
+	return err
+}
+
+const revokeOtherUserSessions = `-- name: RevokeOtherUserSessions :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND family_id <> $2
+  AND revoked_at IS NULL
+`
+
+type RevokeOtherUserSessionsParams struct {
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
+}
+
+func (q *Queries) RevokeOtherUserSessions(ctx context.Context, arg RevokeOtherUserSessionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeOtherUserSessions, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1
+WHERE token_hash = $1
+`
+
+func (q *Queries) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshToken, tokenHash)
+	return err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+  AND revoked_at IS NULL
 `
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
 	return err
 }
+
+const revokeUserSession = `-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND family_id = $2
+  AND revoked_at IS NULL
+`
+
+type RevokeUserSessionParams struct {
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
+}
+
+func (q *Queries) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserSession, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
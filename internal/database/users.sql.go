@@ -11,6 +11,38 @@ import (
 	"github.com/google/uuid"
 )
 
+const bootstrapAdmin = `-- name: BootstrapAdmin :execrows
+UPDATE users
+SET role = 'admin',
+    updated_at = NOW()
+WHERE email = $1
+  AND email_verified_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin')
+`
+
+func (q *Queries) BootstrapAdmin(ctx context.Context, email string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, bootstrapAdmin, email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const bumpUserTokenVersion = `-- name: BumpUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING token_version
+`
+
+func (q *Queries) BumpUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, bumpUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
 VALUES (
@@ -21,7 +53,7 @@ VALUES (
     $2,
     FALSE
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
 `
 
 type CreateUserParams struct {
@@ -39,12 +71,22 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
  FROM users
  WHERE email = $1
 `
@@ -59,6 +101,223 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getUserByEmailForUpdate = `-- name: GetUserByEmailForUpdate :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
+ FROM users
+ WHERE email = $1
+ FOR UPDATE
+`
+
+func (q *Queries) GetUserByEmailForUpdate(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmailForUpdate, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
+ FROM users
+ WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getUserByIDForUpdate = `-- name: GetUserByIDForUpdate :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
+ FROM users
+ WHERE id = $1
+ FOR UPDATE
+`
+
+func (q *Queries) GetUserByIDForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIDForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getUserTokenVersion = `-- name: GetUserTokenVersion :one
+SELECT token_version FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET token_version = CASE WHEN role = $2 THEN token_version ELSE token_version + 1 END,
+    role = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = NOW(),
+    token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, unsuspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
@@ -67,9 +326,10 @@ const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET email = $2,
     hashed_password = $3,
+    email_verified_at = CASE WHEN email = $2 THEN email_verified_at END,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
 `
 
 type UpdateUserParams struct {
@@ -88,15 +348,42 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
 const upgradeUser = `-- name: UpgradeUser :one
 UPDATE users
 SET is_chirpy_red = TRUE
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, role, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_counter, failed_login_count, last_failed_login_at, locked_until
 `
 
 func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (User, error) {
@@ -109,6 +396,16 @@ func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
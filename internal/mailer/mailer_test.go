@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := FileMailer{Dir: dir}
+
+	err := m.Send(context.Background(), Message{
+		To:      "someone/../@example.com",
+		Subject: "Hello",
+		Body:    "token: abc123",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error sending: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 message file, got %d", len(entries))
+	}
+	if name := entries[0].Name(); strings.Contains(name, "/") || !strings.HasSuffix(name, "-someone_.._@example.com.eml") {
+		t.Errorf("unexpected file name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: someone/../@example.com", "Subject: Hello", "token: abc123"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("message file missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := LogMailer{Logger: log.New(&buf, "", 0)}
+
+	if err := m.Send(context.Background(), Message{To: "a@example.com", Subject: "Hi", Body: "body"}); err != nil {
+		t.Fatalf("unexpected error sending: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "a@example.com") || !strings.Contains(out, "body") {
+		t.Errorf("unexpected log output %q", out)
+	}
+}
@@ -0,0 +1,183 @@
+package moderation
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Action is what happens to a chirp containing a listed word.
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionReject Action = "reject"
+	ActionFlag   Action = "flag"
+)
+
+const mask = "****"
+
+var ErrInvalidAction = errors.New("action must be one of mask, reject or flag")
+
+// ParseAction validates an action read from a request or the database.
+func ParseAction(s string) (Action, error) {
+	switch a := Action(s); a {
+	case ActionMask, ActionReject, ActionFlag:
+		return a, nil
+	}
+	return "", ErrInvalidAction
+}
+
+// Rule is a single entry in the moderation word list.
+type Rule struct {
+	Word   string
+	Action Action
+}
+
+// Filter matches chirp bodies against a word list.
+type Filter struct {
+	rules map[string]Action
+}
+
+// Result is the outcome of checking a body.
+type Result struct {
+	// Body is the input with every mask word replaced by ****.
+	Body string
+	// Rejected lists the reject words found. The chirp must not be stored
+	// when it is non-empty.
+	Rejected []string
+	// Flagged lists the flag words found. The chirp is stored unchanged
+	// but should be queued for review.
+	Flagged []string
+}
+
+// NewFilter builds a filter from rules. Rule words are normalized the same
+// way as chirp text, so "Fornax" and "f0rnax" are the same rule.
+func NewFilter(rules []Rule) *Filter {
+	f := &Filter{rules: make(map[string]Action, len(rules))}
+	for _, r := range rules {
+		if w := Normalize(r.Word); w != "" {
+			f.rules[w] = r.Action
+		}
+	}
+	return f
+}
+
+// Check looks at every word in body, where a word is a run of letters,
+// digits and leetspeak symbols, so anything else separates words: spaces,
+// newlines, hyphens and punctuation alike. Masking only replaces the word
+// itself, so "Kerfuffle!" becomes "****!".
+func (f *Filter) Check(body string) Result {
+	var res Result
+	var b strings.Builder
+	pos := 0
+	for _, word := range strings.FieldsFunc(body, func(r rune) bool { return !isWordRune(r) }) {
+		// fields come back in order and are separated by non-word runes,
+		// so the next match from pos is this word's own position
+		start := pos + strings.Index(body[pos:], word)
+		b.WriteString(body[pos:start])
+		pos = start + len(word)
+
+		norm := Normalize(word)
+		switch f.rules[norm] {
+		case ActionMask:
+			b.WriteString(mask)
+			continue
+		case ActionReject:
+			res.Rejected = append(res.Rejected, norm)
+		case ActionFlag:
+			res.Flagged = append(res.Flagged, norm)
+		}
+		b.WriteString(word)
+	}
+	b.WriteString(body[pos:])
+	res.Body = b.String()
+	return res
+}
+
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return true
+	}
+	_, ok := leet[r]
+	return ok
+}
+
+// leet maps common character substitutions back to the letter they stand for.
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+}
+
+// Normalize reduces a word to the form used for matching: fullwidth and
+// accented Latin letters folded to plain ASCII, combining marks dropped,
+// lower-cased, leetspeak undone and any other punctuation removed.
+func Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		r = foldRune(r)
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if l, ok := leet[r]; ok {
+			r = l
+		}
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// foldRune maps fullwidth ASCII and precomposed accented Latin letters to
+// their base letter. It covers what people use to dodge a word filter
+// without pulling in a full Unicode normalization table.
+func foldRune(r rune) rune {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		return r - 0xFEE0
+	}
+	if base, ok := accents[r]; ok {
+		return base
+	}
+	return r
+}
+
+var accents = func() map[rune]rune {
+	groups := map[rune]string{
+		'a': "àáâãäåāăąǎ",
+		'c': "çćĉċč",
+		'd': "ďđ",
+		'e': "èéêëēĕėęě",
+		'g': "ĝğġģ",
+		'h': "ĥħ",
+		'i': "ìíîïĩīĭįı",
+		'j': "ĵ",
+		'k': "ķ",
+		'l': "ĺļľŀł",
+		'n': "ñńņňŉ",
+		'o': "òóôõöøōŏőǒ",
+		'r': "ŕŗř",
+		's': "śŝşšſ",
+		't': "ţťŧ",
+		'u': "ùúûüũūŭůűųǔ",
+		'w': "ŵ",
+		'y': "ýÿŷ",
+		'z': "źżž",
+	}
+	m := map[rune]rune{}
+	for base, chars := range groups {
+		for _, c := range chars {
+			m[c] = base
+			m[unicode.ToUpper(c)] = unicode.ToUpper(base)
+		}
+	}
+	return m
+}()
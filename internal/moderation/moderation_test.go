@@ -0,0 +1,110 @@
+package moderation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Kerfuffle", "kerfuffle"},
+		{"SHARBERT", "sharbert"},
+		{"f0rn4x", "fornax"},
+		{"$h@rb3rt", "sharbert"},
+		{"k.e.r.f.u.f.f.l.e", "kerfuffle"},
+		{"fórnäx", "fornax"},
+		{"fo\u0301rnax", "fornax"}, // combining acute on the o
+		{"ｆｏｒｎａｘ", "fornax"},       // fullwidth
+		{"...", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilterCheck(t *testing.T) {
+	f := NewFilter([]Rule{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "Sharbert", Action: ActionMask},
+		{Word: "fornax", Action: ActionReject},
+		{Word: "blorp", Action: ActionFlag},
+	})
+
+	tests := []struct {
+		name         string
+		body         string
+		wantBody     string
+		wantRejected []string
+		wantFlagged  []string
+	}{
+		{
+			name:     "clean body is unchanged",
+			body:     "I had something interesting for breakfast",
+			wantBody: "I had something interesting for breakfast",
+		},
+		{
+			name:     "mask keeps surrounding punctuation",
+			body:     "What a Kerfuffle! (sharbert)",
+			wantBody: "What a ****! (****)",
+		},
+		{
+			name:     "leetspeak is masked",
+			body:     "k3rfuffl3 again",
+			wantBody: "**** again",
+		},
+		{
+			name:         "reject word is reported",
+			body:         "go away F0RNAX.",
+			wantBody:     "go away F0RNAX.",
+			wantRejected: []string{"fornax"},
+		},
+		{
+			name:        "flag word is reported and kept",
+			body:        "such a blorp",
+			wantBody:    "such a blorp",
+			wantFlagged: []string{"blorp"},
+		},
+		{
+			name:        "any non-word rune separates words",
+			body:        "kerfuffle-sharbert\nsharbert\tkerfuffle/blorp",
+			wantBody:    "****-****\n****\t****/blorp",
+			wantFlagged: []string{"blorp"},
+		},
+		{
+			name:     "substring is not a match",
+			body:     "kerfuffles happen",
+			wantBody: "kerfuffles happen",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.Check(tt.body)
+			if got.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", got.Body, tt.wantBody)
+			}
+			if !reflect.DeepEqual(got.Rejected, tt.wantRejected) {
+				t.Errorf("Rejected = %v, want %v", got.Rejected, tt.wantRejected)
+			}
+			if !reflect.DeepEqual(got.Flagged, tt.wantFlagged) {
+				t.Errorf("Flagged = %v, want %v", got.Flagged, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	for _, s := range []string{"mask", "reject", "flag"} {
+		if _, err := ParseAction(s); err != nil {
+			t.Errorf("ParseAction(%q) returned error: %v", s, err)
+		}
+	}
+	if _, err := ParseAction("delete"); err == nil {
+		t.Error("expected error for unknown action, got nil")
+	}
+}
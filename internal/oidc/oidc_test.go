@@ -0,0 +1,192 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockProvider is a local OpenID provider that issues one authorization
+// code per AuthCodeURL and checks the PKCE verifier when it is redeemed.
+type mockProvider struct {
+	t         *testing.T
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	clientID  string
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{t: t, key: key, clientID: "chirpy"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		enc := base64.RawURLEncoding
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   enc.EncodeToString(key.N.Bytes()),
+			"e":   enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != m.clientID || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.FormValue("code") != "the-code" || pkceChallenge(r.FormValue("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":            m.server.URL,
+			"aud":            m.clientID,
+			"sub":            "user-1",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"nonce":          m.nonce,
+			"email":          "walt@example.com",
+			"email_verified": true,
+		}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Error(err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": signed})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:       m.server.URL,
+		ClientID:     m.clientID,
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:8080/api/oidc/mock/callback",
+	}, m.server.Client())
+}
+
+// authorize plays the user's trip to the provider: it records what the
+// authorization request asked for.
+func (m *mockProvider) authorize(authURL string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != m.clientID {
+		m.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+	m.challenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce-1", challenge)
+	if err != nil {
+		t.Fatalf("unexpected error building auth URL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, m.server.URL+"/authorize?") {
+		t.Errorf("auth URL %q does not use the discovered endpoint", authURL)
+	}
+	m.authorize(authURL)
+
+	id, err := p.Exchange(ctx, "the-code", verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("unexpected error exchanging code: %v", err)
+	}
+	if id.Subject != "user-1" || id.Email != "walt@example.com" || !id.EmailVerified {
+		t.Errorf("unexpected ID token %+v", id)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		verifier string
+		nonce    string
+	}{
+		{name: "wrong verifier", verifier: "not-the-verifier", nonce: "nonce-1"},
+		{name: "wrong nonce", nonce: "nonce-2"},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "someone-else"}, nonce: "nonce-1"},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example.com"}, nonce: "nonce-1"},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, nonce: "nonce-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			m.claims = tt.claims
+			p := m.provider()
+			ctx := context.Background()
+
+			verifier, challenge, err := NewPKCE()
+			if err != nil {
+				t.Fatal(err)
+			}
+			authURL, err := p.AuthCodeURL(ctx, "state", "nonce-1", challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.authorize(authURL)
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			if _, err := p.Exchange(ctx, "the-code", verifier, tt.nonce); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockProvider(t)
+	p := NewProvider(Config{Issuer: m.server.URL + "/other", ClientID: m.clientID}, m.server.Client())
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Error("expected discovery to fail, got nil")
+	}
+}
@@ -0,0 +1,45 @@
+// Package throttle computes exponential backoff for repeated failures, such
+// as wrong passwords.
+package throttle
+
+import "time"
+
+// Policy describes how quickly failures are slowed down.
+type Policy struct {
+	// FreeAttempts is how many failures in a row are allowed without delay.
+	FreeAttempts int
+	// BaseDelay is the wait after the first failure past FreeAttempts. Each
+	// further failure doubles it.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait.
+	MaxDelay time.Duration
+}
+
+// Delay returns how long to wait after failures consecutive failures.
+func (p Policy) Delay(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := 1; i < over; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// RetryAfter returns how long from now until another attempt is allowed,
+// or zero if one is allowed now.
+func (p Policy) RetryAfter(failures int, lastFailure, now time.Time) time.Duration {
+	next := lastFailure.Add(p.Delay(failures))
+	if !next.After(now) {
+		return 0
+	}
+	return next.Sub(now)
+}
@@ -0,0 +1,43 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	p := Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	p := Policy{FreeAttempts: 1, BaseDelay: 4 * time.Second, MaxDelay: time.Minute}
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := p.RetryAfter(2, last, last.Add(time.Second)); got != 3*time.Second {
+		t.Errorf("expected 3s left, got %v", got)
+	}
+	if got := p.RetryAfter(2, last, last.Add(4*time.Second)); got != 0 {
+		t.Errorf("expected no wait once the delay passed, got %v", got)
+	}
+	if got := p.RetryAfter(1, last, last); got != 0 {
+		t.Errorf("expected no wait for free attempts, got %v", got)
+	}
+}
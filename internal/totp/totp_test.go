@@ -0,0 +1,92 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 appendix B.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	// the RFC lists eight digit codes; six digits are their last six
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := Code(rfcSecret, Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counter, ok := Validate(code, rfcSecret, now, 0)
+	if !ok || counter != Counter(now) {
+		t.Fatalf("expected current code to validate, got %d %v", counter, ok)
+	}
+	if _, ok := Validate(code, rfcSecret, now, counter); ok {
+		t.Error("expected a used code to be rejected")
+	}
+	if _, ok := Validate(code, rfcSecret, now.Add(Period), 0); !ok {
+		t.Error("expected code from the previous period to validate")
+	}
+	if _, ok := Validate(code, rfcSecret, now.Add(3*Period), 0); ok {
+		t.Error("expected an old code to be rejected")
+	}
+	if _, ok := Validate("12345", rfcSecret, now, 0); ok {
+		t.Error("expected a short code to be rejected")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("expected two secrets to differ")
+	}
+	if _, err := Code(a, 1); err != nil {
+		t.Errorf("generated secret is not usable: %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Chirpy", "a b@example.com", "JBSWY3DPEHPK3PXP")
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid URI %q: %v", uri, err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Chirpy:a b@example.com" {
+		t.Errorf("unexpected URI %q", uri)
+	}
+	q := u.Query()
+	if q.Get("secret") != "JBSWY3DPEHPK3PXP" || q.Get("issuer") != "Chirpy" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
@@ -2,23 +2,155 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 )
 
-func respondWithError(w http.ResponseWriter, code int, msg string, err error) {
+// Stable error codes. Clients switch on these; the detail text is for
+// people and may change.
+const (
+	codeBadRequest               = "bad_request"
+	codeInvalidBody              = "invalid_body"
+	codeValidationFailed         = "validation_failed"
+	codeUnauthenticated          = "unauthenticated"
+	codeInvalidToken             = "invalid_token"
+	codeInvalidCredentials       = "invalid_credentials"
+	codeInvalidCode              = "invalid_code"
+	codeInvalidMFAToken          = "invalid_mfa_token"
+	codeInvalidAPIKey            = "invalid_api_key"
+	codeInvalidLoginState        = "invalid_login_state"
+	codeInvalidVerificationToken = "invalid_verification_token"
+	codeInvalidResetToken        = "invalid_reset_token"
+	codeInsufficientScope        = "insufficient_scope"
+	codeForbidden                = "forbidden"
+	codeAccountSuspended         = "account_suspended"
+	codeEmailNotVerified         = "email_not_verified"
+	codeNotFound                 = "not_found"
+	codeMethodNotAllowed         = "method_not_allowed"
+	codeConflict                 = "conflict"
+	codeTooManyRequests          = "too_many_requests"
+	codeInternal                 = "internal_error"
+	codeBadGateway               = "bad_gateway"
+	codeServiceUnavailable       = "service_unavailable"
+)
+
+// defaultErrorCodes is the code an error gets when the handler doesn't
+// pick a more specific one.
+var defaultErrorCodes = map[int]string{
+	http.StatusBadRequest:          codeBadRequest,
+	http.StatusUnauthorized:        codeUnauthenticated,
+	http.StatusForbidden:           codeForbidden,
+	http.StatusNotFound:            codeNotFound,
+	http.StatusMethodNotAllowed:    codeMethodNotAllowed,
+	http.StatusConflict:            codeConflict,
+	http.StatusTooManyRequests:     codeTooManyRequests,
+	http.StatusInternalServerError: codeInternal,
+	http.StatusBadGateway:          codeBadGateway,
+	http.StatusServiceUnavailable:  codeServiceUnavailable,
+}
+
+// problem is an RFC 9457 problem details object, sent as
+// application/problem+json for every error response.
+type problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []fieldError `json:"errors,omitempty"`
+}
+
+func newProblem(w http.ResponseWriter, status int, code, msg string) problem {
+	if code == "" {
+		code = defaultErrorCodes[status]
+	}
+	if code == "" {
+		code = codeInternal
+	}
+	return problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    msg,
+		Code:      code,
+		RequestID: w.Header().Get(requestIDHeader),
+	}
+}
+
+// respondWithError sends a problem with the default code for status.
+func respondWithError(w http.ResponseWriter, status int, msg string, err error) {
+	respondWithProblem(w, status, "", msg, err)
+}
+
+// respondWithProblem sends a problem with a specific code. err is only
+// logged, never sent.
+func respondWithProblem(w http.ResponseWriter, status int, code, msg string, err error) {
+	writeProblem(w, newProblem(w, status, code, msg), err)
+}
+
+// writeProblem logs and sends p.
+func writeProblem(w http.ResponseWriter, p problem, err error) {
+	logProblem(p, err)
+	writeProblemJSON(w, p.Status, p)
+}
+
+// logProblem logs the cause of a problem, and every server error.
+func logProblem(p problem, err error) {
 	if err != nil {
-		log.Println(err)
+		log.Printf("request %s: %v", p.RequestID, err)
 	}
-	if code > 499 {
-		log.Printf("Responding with 5XX error: %s", msg)
+	if p.Status > 499 {
+		log.Printf("request %s: responding with %d", p.RequestID, p.Status)
 	}
-	type errorResponse struct {
-		Error string `json:"error"`
+}
+
+// writeProblemJSON sends body, a problem or a type embedding one, as
+// application/problem+json.
+func writeProblemJSON(w http.ResponseWriter, status int, body any) {
+	dat, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %s", err)
+		w.WriteHeader(500)
+		return
 	}
-	respondWithJSON(w, code, errorResponse{
-		Error: msg,
-	})
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	w.Write(dat)
+}
+
+// fieldError is one problem with one field of a request body.
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error lets a helper that checks a single field, such as parsePageParams,
+// return what was wrong with it.
+func (e fieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// respondWithInputError answers an error from such a helper: a fieldError
+// is the client's mistake and gets a validation problem, anything else is a
+// server error.
+func respondWithInputError(w http.ResponseWriter, msg string, err error) {
+	var invalid fieldError
+	if errors.As(err, &invalid) {
+		respondWithValidationErrors(w, msg, []fieldError{invalid})
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+}
+
+// respondWithValidationErrors sends a 400 listing every field problem so
+// clients can show them next to the right inputs.
+func respondWithValidationErrors(w http.ResponseWriter, msg string, errs []fieldError) {
+	p := newProblem(w, http.StatusBadRequest, codeValidationFailed, msg)
+	p.Errors = errs
+	writeProblem(w, p, nil)
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
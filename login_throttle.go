@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/throttle"
+	"github.com/google/uuid"
+)
+
+// Outcomes stored in login_attempts.outcome.
+const (
+	loginSuccess   = "success"
+	loginFailure   = "failure"
+	loginThrottled = "throttled"
+	loginLocked    = "locked"
+)
+
+// ipFailureWindow is how far back failed logins from one IP are counted.
+const ipFailureWindow = 15 * time.Minute
+
+var (
+	// accountLoginPolicy slows down guessing against a single account.
+	accountLoginPolicy = throttle.Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Minute}
+	// ipLoginPolicy is looser because many users can share an address, but
+	// still slows down one client trying many accounts.
+	ipLoginPolicy = throttle.Policy{FreeAttempts: 20, BaseDelay: time.Second, MaxDelay: 15 * time.Minute}
+)
+
+// loginLockout locks an account for duration once it reaches threshold
+// failed logins in a row. Admins can unlock it early.
+type loginLockout struct {
+	threshold int32
+	duration  time.Duration
+}
+
+// recordLoginAttempt audits a login. Failing to record it does not fail the
+// login itself.
+func (cfg *apiConfig) recordLoginAttempt(ctx context.Context, r *http.Request, email string, userID uuid.UUID, outcome string) {
+	err := cfg.queries.RecordLoginAttempt(ctx, database.RecordLoginAttemptParams{
+		Email:   email,
+		UserID:  uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		Ip:      clientIP(r),
+		Outcome: outcome,
+	})
+	if err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+}
+
+// ipLoginRetryAfter returns how long the request's IP has to wait before
+// trying to log in again.
+func (cfg *apiConfig) ipLoginRetryAfter(ctx context.Context, r *http.Request) (time.Duration, error) {
+	now := time.Now()
+	recent, err := cfg.queries.GetRecentFailedLoginsByIP(ctx, database.GetRecentFailedLoginsByIPParams{
+		Ip:        clientIP(r),
+		CreatedAt: now.Add(-ipFailureWindow),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ipLoginPolicy.RetryAfter(int(recent.Failures), recent.LastFailure, now), nil
+}
+
+// accountThrottled answers with a 429 and returns true when user has to
+// wait before trying a password or second factor again. The caller should
+// hold the user's row lock, so concurrent attempts see each other's failures.
+func (cfg *apiConfig) accountThrottled(w http.ResponseWriter, r *http.Request, user database.User) bool {
+	wait, locked := accountLoginRetryAfter(user, time.Now())
+	if wait <= 0 {
+		return false
+	}
+	outcome := loginThrottled
+	if locked {
+		outcome = loginLocked
+	}
+	cfg.recordLoginAttempt(r.Context(), r, user.Email, user.ID, outcome)
+	respondTooManyAttempts(w, wait)
+	return true
+}
+
+// recordFailedLogin counts a wrong password or second factor against
+// userID, locking the account once it reaches the lockout threshold.
+func (cfg *apiConfig) recordFailedLogin(ctx context.Context, q *database.Queries, userID uuid.UUID) error {
+	_, err := q.RecordFailedLogin(ctx, database.RecordFailedLoginParams{
+		LockoutThreshold: cfg.lockout.threshold,
+		LockedUntil:      time.Now().Add(cfg.lockout.duration),
+		ID:               userID,
+	})
+	return err
+}
+
+// accountLoginRetryAfter returns how long user has to wait before the next
+// password attempt, either because it is locked or backing off.
+func accountLoginRetryAfter(user database.User, now time.Time) (wait time.Duration, locked bool) {
+	if user.LockedUntil.Valid && user.LockedUntil.Time.After(now) {
+		return user.LockedUntil.Time.Sub(now), true
+	}
+	if !user.LastFailedLoginAt.Valid {
+		return 0, false
+	}
+	return accountLoginPolicy.RetryAfter(int(user.FailedLoginCount), user.LastFailedLoginAt.Time, now), false
+}
+
+// respondTooManyAttempts sends a 429 telling the client when to try again.
+func respondTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	respondRetryAfter(w, http.StatusTooManyRequests, wait, "Too many login attempts, try again later")
+}
+
+// respondRetryAfter sends status with a Retry-After header of wait, rounded
+// up to whole seconds.
+func respondRetryAfter(w http.ResponseWriter, status int, wait time.Duration, msg string) {
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+	respondWithError(w, status, msg, nil)
+}
+
+// handlerUnlockUser clears a lockout and the failed login count.
+func (cfg *apiConfig) handlerUnlockUser(w http.ResponseWriter, r *http.Request) {
+	cfg.moderateUser(w, r, modActionUnlock, (*database.Queries).UnlockUser)
+}
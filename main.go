@@ -1,25 +1,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"sync/atomic"
 	"time"
 
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
 	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/mailer"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/oidc"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	queries        *database.Queries
-	platform       string
-	JWTSecret      string
-	POLKAKey       string
+	fileserverHits  atomic.Int32
+	db              *sql.DB
+	queries         *database.Queries
+	platform        string
+	JWTKeys         *auth.KeySet
+	revocations     *tokenRevocations
+	POLKAKey        string
+	chirpEditWindow time.Duration
+	adminEmail      string
+	words           wordFilterCache
+	mailer          mailer.Mailer
+	lockout         loginLockout
+	passwordPolicy  auth.PasswordPolicy
+	oidcProviders   map[string]*oidc.Provider
+	// resetSends holds a slot for every password reset email being sent
+	resetSends chan struct{}
 }
 
 // Need a struct to help make users
@@ -32,7 +48,6 @@ type User struct {
 
 func main() {
 	godotenv.Load()
-	const filepathRoot = "."
 	const port = "8080"
 
 	dbURL := os.Getenv("DB_URL")
@@ -43,56 +58,124 @@ func main() {
 	if platformString == "" {
 		log.Fatal("PLATFORM must be set")
 	}
-	JWTSecret := os.Getenv("JWT_SECRET")
-	if JWTSecret == "" {
-		log.Fatal("JWT_SECRET must be set")
+	// JWT_KEYS_DIR switches token signing from the shared HS256 secret to
+	// the RSA/Ed25519 keys in that directory, published at /.well-known/jwks.json.
+	var JWTKeys *auth.KeySet
+	if keysDir := os.Getenv("JWT_KEYS_DIR"); keysDir != "" {
+		keys, err := auth.LoadKeySet(keysDir, os.Getenv("JWT_SIGNING_KEY_ID"))
+		if err != nil {
+			log.Fatalf("Failed to load JWT keys: %v", err)
+		}
+		JWTKeys = keys
+	} else {
+		JWTSecret := os.Getenv("JWT_SECRET")
+		if JWTSecret == "" {
+			log.Fatal("JWT_SECRET or JWT_KEYS_DIR must be set")
+		}
+		JWTKeys = auth.NewHMACKeySet(JWTSecret)
 	}
 	POLKAKey := os.Getenv("POLKA_KEY")
 	if POLKAKey == "" {
 		log.Fatal("POLKA_KEY must be set")
 	}
+	chirpEditWindow := 15 * time.Minute
+	if s := os.Getenv("CHIRP_EDIT_WINDOW"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			log.Fatalf("CHIRP_EDIT_WINDOW must be a duration like 15m: %v", err)
+		}
+		chirpEditWindow = d
+	}
+
+	lockout := loginLockout{threshold: 10, duration: 15 * time.Minute}
+	if s := os.Getenv("LOGIN_LOCKOUT_THRESHOLD"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			log.Fatalf("LOGIN_LOCKOUT_THRESHOLD must be a positive number: %q", s)
+		}
+		lockout.threshold = int32(n)
+	}
+	if s := os.Getenv("LOGIN_LOCKOUT_DURATION"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			log.Fatalf("LOGIN_LOCKOUT_DURATION must be a duration like 15m: %v", err)
+		}
+		lockout.duration = d
+	}
+
+	passwordPolicy := auth.PasswordPolicy{MinLength: defaultPasswordMinLength}
+	if s := os.Getenv("PASSWORD_MIN_LENGTH"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			log.Fatalf("PASSWORD_MIN_LENGTH must be a positive number: %q", s)
+		}
+		passwordPolicy.MinLength = n
+	}
+	// BREACHED_PASSWORDS_FILE holds SHA-1 hashes of known breached passwords
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		list, err := auth.LoadBreachedList(path)
+		if err != nil {
+			log.Fatalf("Failed to load breached passwords: %v", err)
+		}
+		passwordPolicy.Breached = list
+	}
+
+	// MAIL_DIR keeps outgoing mail as files there; otherwise it is only logged
+	var mail mailer.Mailer = mailer.LogMailer{}
+	if dir := os.Getenv("MAIL_DIR"); dir != "" {
+		mail = mailer.FileMailer{Dir: dir}
+	}
+
+	oidcProviders, err := loadOIDCProviders()
+	if err != nil {
+		log.Fatalf("Failed to configure OIDC providers: %v", err)
+	}
 
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	dbQueries := database.New(db)
+	revocations := newTokenRevocations(dbQueries)
+	if err := revocations.sync(context.Background()); err != nil {
+		log.Fatalf("Failed to load the access token denylist: %v", err)
+	}
+	go revocations.run(context.Background())
+	JWTKeys.SetRevocationChecker(revocations)
+	JWTKeys.SetPersonalTokenResolver(personalTokens{queries: dbQueries})
 	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		queries:        dbQueries,
-		platform:       platformString,
-		JWTSecret:      JWTSecret,
-		POLKAKey:       POLKAKey,
+		fileserverHits:  atomic.Int32{},
+		db:              db,
+		queries:         dbQueries,
+		platform:        platformString,
+		JWTKeys:         JWTKeys,
+		revocations:     revocations,
+		POLKAKey:        POLKAKey,
+		chirpEditWindow: chirpEditWindow,
+		adminEmail:      os.Getenv("ADMIN_EMAIL"),
+		mailer:          mail,
+		lockout:         lockout,
+		passwordPolicy:  passwordPolicy,
+		oidcProviders:   oidcProviders,
+		resetSends:      make(chan struct{}, maxPasswordResetSends),
 	}
 
-	mux := http.NewServeMux()
-	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
-	mux.Handle("/app/", fsHandler)
-
-	//Get
-	mux.HandleFunc("GET /api/healthz", handlerReadiness)
-	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetAllChirps)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerGetChirp)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
-
-	//Post
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerCreateChirp)
-	mux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
-	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
-	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handler_webhook)
-
-	//Put
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateUser)
-
-	//Delete
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
+	// ADMIN_EMAIL names the account that becomes the first admin, either now
+	// if it already exists with that address verified, or when it verifies
+	// it. It is ignored once any admin exists.
+	if apiCfg.adminEmail != "" {
+		promoted, err := dbQueries.BootstrapAdmin(context.Background(), apiCfg.adminEmail)
+		if err != nil {
+			log.Fatalf("Failed to bootstrap admin: %v", err)
+		}
+		if promoted > 0 {
+			log.Printf("Promoted %s to admin", apiCfg.adminEmail)
+		}
+	}
 
 	srv := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: apiCfg.routes(),
 	}
 
 	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const principalContextKey contextKey = "principal"
+
+// Token types a caller can authenticate with.
+const (
+	// tokenTypeSession is an access token from the user's own login.
+	tokenTypeSession = "session"
+	// tokenTypeOAuth is an access token issued to an OAuth client.
+	tokenTypeOAuth = "oauth"
+	// tokenTypePersonal is a personal access token.
+	tokenTypePersonal = "personal"
+)
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	UserID    uuid.UUID
+	Role      string
+	Scopes    []string
+	TokenType string
+	Claims    *auth.Claims
+}
+
+func newPrincipal(claims *auth.Claims) (*principal, error) {
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	p := &principal{
+		UserID:    userID,
+		Role:      claims.Role,
+		Scopes:    strings.Fields(claims.Scope),
+		TokenType: tokenTypeSession,
+		Claims:    claims,
+	}
+	switch {
+	case claims.PersonalTokenID != "":
+		p.TokenType = tokenTypePersonal
+	case claims.ClientID != "":
+		p.TokenType = tokenTypeOAuth
+	}
+	return p, nil
+}
+
+// authPolicy says what a route needs from its caller.
+type authPolicy struct {
+	// optional lets anonymous requests through with no principal.
+	optional bool
+	// scope lets delegated tokens (OAuth and personal) through when they
+	// grant it. Without a scope only the user's own session tokens pass.
+	scope string
+	// anyToken lets every valid token through, delegated or not.
+	anyToken bool
+	// role is the least privileged role allowed, if any.
+	role string
+}
+
+// middlewareAuth authenticates the bearer token once and stores the caller
+// in the request context, where handlers read it with principalFromContext.
+// Failures get an RFC 6750 WWW-Authenticate challenge. On optional routes a
+// missing token, or a delegated one without the scope, is anonymous, but a
+// bad token is still refused so clients learn it needs replacing.
+func (cfg *apiConfig) middlewareAuth(policy authPolicy, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			if policy.optional && r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			respondUnauthorized(w, "", "Failed to Get token", err)
+			return
+		}
+		claims, err := auth.ParseJWTContext(r.Context(), token, cfg.JWTKeys)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			respondUnauthorized(w, codeInvalidToken, "Invalid token", err)
+			return
+		}
+		if err != nil {
+			// the token may be fine, so don't tell the client to replace it
+			respondWithError(w, http.StatusInternalServerError, "Failed to verify token", err)
+			return
+		}
+		p, err := newPrincipal(claims)
+		if err != nil {
+			respondUnauthorized(w, codeInvalidToken, "Invalid token", err)
+			return
+		}
+
+		if claims.Delegated() && !policy.anyToken {
+			if policy.scope == "" || !claims.HasScope(policy.scope) {
+				if policy.optional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				respondInsufficientScope(w, policy.scope)
+				return
+			}
+		}
+		if policy.role != "" && !auth.RoleAtLeast(p.Role, policy.role) {
+			respondWithError(w, http.StatusForbidden, "Your role does not allow this", nil)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), principalContextKey, p)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// middlewareRequireRole only lets requests through whose access token
+// carries role or a more privileged one.
+func (cfg *apiConfig) middlewareRequireRole(role string, next http.HandlerFunc) http.Handler {
+	return cfg.middlewareAuth(authPolicy{role: role}, next)
+}
+
+// respondUnauthorized sends a 401 with a Bearer challenge. errCode is the
+// RFC 6750 error code, left out when the request had no token at all.
+func respondUnauthorized(w http.ResponseWriter, errCode, msg string, err error) {
+	challenge := `Bearer realm="chirpy"`
+	if errCode != "" {
+		challenge += fmt.Sprintf(`, error=%q, error_description=%q`, errCode, msg)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	code := codeUnauthenticated
+	if errCode != "" {
+		code = errCode
+	}
+	respondWithProblem(w, http.StatusUnauthorized, code, msg, err)
+}
+
+// respondInsufficientScope sends a 403 naming the scope the token lacks.
+// An empty scope means the route takes no delegated tokens at all.
+func respondInsufficientScope(w http.ResponseWriter, scope string) {
+	msg := "This endpoint does not accept delegated tokens"
+	challenge := `Bearer realm="chirpy", error="insufficient_scope"`
+	if scope != "" {
+		msg = "Token does not grant the " + scope + " scope"
+		challenge += fmt.Sprintf(`, scope=%q`, scope)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	respondWithProblem(w, http.StatusForbidden, codeInsufficientScope, msg, auth.ErrInsufficientScope)
+}
+
+// principalFromContext returns the caller stored by middlewareAuth. It is
+// never nil on routes that require authentication, and nil for anonymous
+// requests to optional ones.
+func principalFromContext(ctx context.Context) *principal {
+	p, _ := ctx.Value(principalContextKey).(*principal)
+	return p
+}
+
+// userIDFromContext returns the caller's user ID stored by middlewareAuth.
+func userIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	p := principalFromContext(ctx)
+	if p == nil {
+		return uuid.Nil, false
+	}
+	return p.UserID, true
+}
+
+// optionalUserID returns the caller's user ID on public endpoints that
+// personalise their output, or uuid.Nil for anonymous requests.
+func optionalUserID(r *http.Request) uuid.UUID {
+	id, _ := userIDFromContext(r.Context())
+	return id
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+var errLookupFailed = errors.New("database is down")
+
+// stubPersonalTokens resolves one personal access token to the given
+// claims, and fails the lookup of another.
+type stubPersonalTokens struct {
+	token, failing string
+	claims         auth.Claims
+}
+
+func (s stubPersonalTokens) ResolvePersonalToken(ctx context.Context, token string) (*auth.Claims, error) {
+	switch token {
+	case s.token:
+		claims := s.claims
+		return &claims, nil
+	case s.failing:
+		return nil, errLookupFailed
+	}
+	return nil, fmt.Errorf("%w: unknown personal access token", auth.ErrInvalidToken)
+}
+
+// stubRevocations revokes every token issued to one user, and fails the
+// check for another.
+type stubRevocations struct {
+	revoked, failing string
+}
+
+func (s stubRevocations) IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	if claims.Subject == s.failing {
+		return false, errLookupFailed
+	}
+	return claims.Subject == s.revoked, nil
+}
+
+func TestMiddlewareAuthPolicies(t *testing.T) {
+	cfg := testConfig(t)
+
+	revokedUser, uncheckableUser := uuid.New(), uuid.New()
+	cfg.JWTKeys.SetRevocationChecker(stubRevocations{revoked: revokedUser.String(), failing: uncheckableUser.String()})
+	pat, unresolvablePAT := auth.PersonalTokenPrefix+"abc", auth.PersonalTokenPrefix+"down"
+	patClaims := auth.Claims{Role: auth.RoleUser, Scope: auth.ScopeChirpsRead, PersonalTokenID: uuid.NewString()}
+	patClaims.Subject = uuid.NewString()
+	cfg.JWTKeys.SetPersonalTokenResolver(stubPersonalTokens{token: pat, failing: unresolvablePAT, claims: patClaims})
+
+	user := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{})
+	moderator := testToken(t, cfg.JWTKeys, auth.RoleModerator, auth.Claims{})
+	admin := testToken(t, cfg.JWTKeys, auth.RoleAdmin, auth.Claims{})
+	oauthRead := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{ClientID: uuid.NewString(), Scope: auth.ScopeChirpsRead})
+	oauthProfile := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{ClientID: uuid.NewString(), Scope: auth.ScopeProfile})
+	oauthModerator := testToken(t, cfg.JWTKeys, auth.RoleModerator, auth.Claims{ClientID: uuid.NewString(), Scope: auth.ScopeChirpsRead})
+	revoked, err := auth.MakeJWT(revokedUser, auth.RoleUser, cfg.JWTKeys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	expired, err := auth.MakeJWT(uuid.New(), auth.RoleUser, cfg.JWTKeys, -time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	uncheckable, err := auth.MakeJWT(uncheckableUser, auth.RoleUser, cfg.JWTKeys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+
+	authed := authPolicy{}
+	scoped := authPolicy{scope: auth.ScopeChirpsRead}
+	optional := authPolicy{scope: auth.ScopeChirpsRead, optional: true}
+	anyToken := authPolicy{anyToken: true}
+	modOnly := authPolicy{role: auth.RoleModerator}
+
+	tests := []struct {
+		name      string
+		policy    authPolicy
+		header    string
+		status    int
+		code      string
+		tokenType string
+	}{
+		{name: "authed without token", policy: authed, status: 401, code: codeUnauthenticated},
+		{name: "authed malformed header", policy: authed, header: "Basic abc", status: 401, code: codeUnauthenticated},
+		{name: "authed bad token", policy: authed, header: "Bearer nope", status: 401, code: codeInvalidToken},
+		{name: "authed expired token", policy: authed, header: "Bearer " + expired, status: 401, code: codeInvalidToken},
+		{name: "authed revoked token", policy: authed, header: "Bearer " + revoked, status: 401, code: codeInvalidToken},
+		{name: "authed session token", policy: authed, header: "Bearer " + user, status: 200, tokenType: tokenTypeSession},
+		{name: "authed oauth token", policy: authed, header: "Bearer " + oauthRead, status: 403, code: codeInsufficientScope},
+		{name: "authed personal token", policy: authed, header: "Bearer " + pat, status: 403, code: codeInsufficientScope},
+		{name: "authed unknown personal token", policy: authed, header: "Bearer " + auth.PersonalTokenPrefix + "nope", status: 401, code: codeInvalidToken},
+		{name: "authed revocation check fails", policy: authed, header: "Bearer " + uncheckable, status: 500, code: codeInternal},
+		{name: "authed personal token lookup fails", policy: authed, header: "Bearer " + unresolvablePAT, status: 500, code: codeInternal},
+		{name: "optional revocation check fails", policy: optional, header: "Bearer " + uncheckable, status: 500, code: codeInternal},
+
+		{name: "scoped session token", policy: scoped, header: "Bearer " + user, status: 200, tokenType: tokenTypeSession},
+		{name: "scoped oauth token with scope", policy: scoped, header: "Bearer " + oauthRead, status: 200, tokenType: tokenTypeOAuth},
+		{name: "scoped oauth token without scope", policy: scoped, header: "Bearer " + oauthProfile, status: 403, code: codeInsufficientScope},
+		{name: "scoped personal token with scope", policy: scoped, header: "Bearer " + pat, status: 200, tokenType: tokenTypePersonal},
+
+		{name: "optional without token", policy: optional, status: 200},
+		{name: "optional malformed header", policy: optional, header: "Basic abc", status: 401, code: codeUnauthenticated},
+		{name: "optional bad token", policy: optional, header: "Bearer nope", status: 401, code: codeInvalidToken},
+		{name: "optional session token", policy: optional, header: "Bearer " + user, status: 200, tokenType: tokenTypeSession},
+		{name: "optional oauth token with scope", policy: optional, header: "Bearer " + oauthRead, status: 200, tokenType: tokenTypeOAuth},
+		{name: "optional oauth token without scope", policy: optional, header: "Bearer " + oauthProfile, status: 200},
+
+		{name: "any token oauth", policy: anyToken, header: "Bearer " + oauthProfile, status: 200, tokenType: tokenTypeOAuth},
+		{name: "any token personal", policy: anyToken, header: "Bearer " + pat, status: 200, tokenType: tokenTypePersonal},
+		{name: "any token without token", policy: anyToken, status: 401, code: codeUnauthenticated},
+
+		{name: "role too low", policy: modOnly, header: "Bearer " + user, status: 403, code: codeForbidden},
+		{name: "role exact", policy: modOnly, header: "Bearer " + moderator, status: 200, tokenType: tokenTypeSession},
+		{name: "role higher", policy: modOnly, header: "Bearer " + admin, status: 200, tokenType: tokenTypeSession},
+		{name: "role on delegated token", policy: modOnly, header: "Bearer " + oauthModerator, status: 403, code: codeInsufficientScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *principal
+			reached := false
+			h := cfg.middlewareAuth(tt.policy, func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+				got = principalFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			rec.Header().Set(requestIDHeader, "abc")
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d (body %q)", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status != 200 {
+				if reached {
+					t.Error("expected the handler not to run")
+				}
+				if p := decodeProblem(t, rec); p.Code != tt.code {
+					t.Errorf("expected code %q, got %q", tt.code, p.Code)
+				}
+				return
+			}
+			if !reached {
+				t.Fatal("expected the handler to run")
+			}
+			if tt.tokenType == "" {
+				if got != nil {
+					t.Errorf("expected an anonymous request, got principal %+v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("expected a principal in the request context")
+			}
+			if got.TokenType != tt.tokenType {
+				t.Errorf("expected token type %q, got %q", tt.tokenType, got.TokenType)
+			}
+			if got.UserID.String() != got.Claims.Subject {
+				t.Errorf("expected user ID %s, got %s", got.Claims.Subject, got.UserID)
+			}
+		})
+	}
+}
+
+func TestNewPrincipal(t *testing.T) {
+	userID := uuid.New()
+	tests := []struct {
+		name      string
+		claims    auth.Claims
+		tokenType string
+		scopes    int
+	}{
+		{"session", auth.Claims{Role: auth.RoleUser}, tokenTypeSession, 0},
+		{"oauth", auth.Claims{ClientID: "client", Scope: "chirps:read chirps:write"}, tokenTypeOAuth, 2},
+		{"personal", auth.Claims{PersonalTokenID: "pat", Scope: "profile"}, tokenTypePersonal, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := tt.claims
+			claims.Subject = userID.String()
+			p, err := newPrincipal(&claims)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.UserID != userID || p.TokenType != tt.tokenType || len(p.Scopes) != tt.scopes {
+				t.Errorf("expected %v %s with %d scopes, got %+v", userID, tt.tokenType, tt.scopes, p)
+			}
+		})
+	}
+
+	if _, err := newPrincipal(&auth.Claims{}); err == nil {
+		t.Error("expected error for claims without a user ID subject, got nil")
+	}
+}
+
+func TestPrincipalFromContextAnonymous(t *testing.T) {
+	if p := principalFromContext(context.Background()); p != nil {
+		t.Errorf("expected nil principal, got %+v", p)
+	}
+	if id := optionalUserID(httptest.NewRequest("GET", "/", nil)); id != uuid.Nil {
+		t.Errorf("expected uuid.Nil, got %v", id)
+	}
+}
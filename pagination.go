@@ -0,0 +1,83 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor marks the last row of a page so the next query can seek past it
+// on (created_at, id) instead of using OFFSET.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// seekArgs returns the cursor as the nullable arguments taken by the keyset
+// queries. A nil cursor yields NULLs, which those queries treat as "first page".
+func (c *pageCursor) seekArgs() (sql.NullTime, uuid.NullUUID) {
+	if c == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}, uuid.NullUUID{UUID: c.ID, Valid: true}
+}
+
+// encodeCursor turns a cursor into the opaque string handed to clients.
+func encodeCursor(c pageCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor reverses encodeCursor.
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	return pageCursor{CreatedAt: t, ID: uid}, nil
+}
+
+// parsePageParams reads the limit and cursor query parameters.
+// cursor is nil when the client is asking for the first page. A bad
+// parameter is reported as a fieldError.
+func parsePageParams(r *http.Request) (limit int32, cursor *pageCursor, err error) {
+	limit = defaultPageSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, nil, fieldError{"limit", "invalid", "Limit must be between 1 and " + strconv.Itoa(maxPageSize)}
+		}
+		limit = int32(n)
+	}
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			return 0, nil, fieldError{"cursor", "invalid", "Malformed cursor"}
+		}
+		cursor = &c
+	}
+	return limit, cursor, nil
+}
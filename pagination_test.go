@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name      string
+		createdAt time.Time
+	}{
+		{"utc", time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)},
+		{"nanoseconds", time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)},
+		{"other zone", time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("EST", -5*60*60))},
+		{"zero time", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(pageCursor{CreatedAt: tt.createdAt, ID: id}))
+			if err != nil {
+				t.Fatalf("unexpected error decoding cursor: %v", err)
+			}
+			if !got.CreatedAt.Equal(tt.createdAt) {
+				t.Errorf("expected created_at %v, got %v", tt.createdAt, got.CreatedAt)
+			}
+			if got.ID != id {
+				t.Errorf("expected id %v, got %v", id, got.ID)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsMalformed(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not base64!"},
+		{"no separator", encode("2024-05-01T12:30:00Z")},
+		{"bad time", encode("yesterday|" + uuid.NewString())},
+		{"bad id", encode("2024-05-01T12:30:00Z|not-a-uuid")},
+		{"empty", encode("|")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor); err == nil {
+				t.Errorf("expected error for cursor %q, got nil", tt.cursor)
+			}
+		})
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	cursor := encodeCursor(pageCursor{CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), ID: uuid.New()})
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int32
+		wantCursor bool
+		wantField  string
+	}{
+		{"defaults", "", defaultPageSize, false, ""},
+		{"limit", "limit=5", 5, false, ""},
+		{"max limit", "limit=100", maxPageSize, false, ""},
+		{"limit too high", "limit=101", 0, false, "limit"},
+		{"limit zero", "limit=0", 0, false, "limit"},
+		{"limit not a number", "limit=ten", 0, false, "limit"},
+		{"cursor", "cursor=" + cursor, defaultPageSize, true, ""},
+		{"bad cursor", "cursor=nope", 0, false, "cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/chirps?"+tt.query, nil)
+			limit, c, err := parsePageParams(r)
+			var invalid fieldError
+			if tt.wantField == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantField != "" && (!errors.As(err, &invalid) || invalid.Field != tt.wantField) {
+				t.Fatalf("expected an error for field %q, got %v", tt.wantField, err)
+			}
+			if limit != tt.wantLimit {
+				t.Errorf("expected limit %d, got %d", tt.wantLimit, limit)
+			}
+			if (c != nil) != tt.wantCursor {
+				t.Errorf("expected cursor: %v, got %+v", tt.wantCursor, c)
+			}
+		})
+	}
+}
+
+func TestSeekArgs(t *testing.T) {
+	var none *pageCursor
+	createdAt, id := none.seekArgs()
+	if createdAt.Valid || id.Valid {
+		t.Errorf("expected NULL seek arguments for the first page, got %v, %v", createdAt, id)
+	}
+
+	c := &pageCursor{CreatedAt: time.Now(), ID: uuid.New()}
+	createdAt, id = c.seekArgs()
+	if !createdAt.Valid || !createdAt.Time.Equal(c.CreatedAt) || !id.Valid || id.UUID != c.ID {
+		t.Errorf("expected seek arguments %v, %v, got %v, %v", c.CreatedAt, c.ID, createdAt, id)
+	}
+}
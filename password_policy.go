@@ -0,0 +1,20 @@
+package main
+
+import "net/http"
+
+const defaultPasswordMinLength = 8
+
+// checkPassword applies the password policy for an account with email. On
+// a violation it writes the validation errors and returns false.
+func (cfg *apiConfig) checkPassword(w http.ResponseWriter, password, email string) bool {
+	violations := cfg.passwordPolicy.Check(password, email)
+	if len(violations) == 0 {
+		return true
+	}
+	errs := make([]fieldError, 0, len(violations))
+	for _, v := range violations {
+		errs = append(errs, fieldError{Field: "password", Code: v.Code, Message: v.Message})
+	}
+	respondWithValidationErrors(w, "Password does not meet the requirements", errs)
+	return false
+}
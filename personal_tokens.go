@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+)
+
+// personalTokenTouchInterval limits last-used tracking to one write per
+// token per interval, so a busy script doesn't write on every request.
+const personalTokenTouchInterval = time.Minute
+
+// personalTokens implements auth.PersonalTokenResolver against the
+// personal_access_tokens table.
+type personalTokens struct {
+	queries *database.Queries
+}
+
+func (p personalTokens) ResolvePersonalToken(ctx context.Context, token string) (*auth.Claims, error) {
+	pat, err := p.queries.GetPersonalAccessTokenByHash(ctx, auth.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: unknown personal access token", auth.ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pat.RevokedAt.Valid {
+		return nil, auth.ErrTokenRevoked
+	}
+	if pat.ExpiresAt.Valid && time.Now().After(pat.ExpiresAt.Time) {
+		return nil, fmt.Errorf("%w: personal access token has expired", auth.ErrInvalidToken)
+	}
+
+	if !pat.LastUsedAt.Valid || time.Since(pat.LastUsedAt.Time) >= personalTokenTouchInterval {
+		if err := p.queries.TouchPersonalAccessToken(ctx, pat.ID); err != nil {
+			log.Printf("Failed to record use of personal access token %s: %v", pat.ID, err)
+		}
+	}
+
+	claims := &auth.Claims{
+		Scope:           strings.Join(pat.Scopes, " "),
+		PersonalTokenID: pat.ID.String(),
+	}
+	claims.Subject = pat.UserID.String()
+	return claims, nil
+}
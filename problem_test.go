@@ -0,0 +1,244 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/mailer"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/oidc"
+	"github.com/google/uuid"
+)
+
+// testConfig returns a config whose database is unreachable, so every
+// request that gets as far as a query fails with a 500.
+func testConfig(t *testing.T) *apiConfig {
+	t.Helper()
+	db, err := sql.Open("postgres", "postgres://chirpy@127.0.0.1:1/chirpy?sslmode=disable&connect_timeout=1")
+	if err != nil {
+		t.Fatalf("unexpected error opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &apiConfig{
+		db:              db,
+		queries:         database.New(db),
+		platform:        "test",
+		JWTKeys:         auth.NewHMACKeySet("super-secret-key"),
+		POLKAKey:        "polka-key",
+		chirpEditWindow: 15 * time.Minute,
+		mailer:          mailer.LogMailer{},
+		lockout:         loginLockout{threshold: 10, duration: 15 * time.Minute},
+		passwordPolicy:  auth.PasswordPolicy{MinLength: defaultPasswordMinLength},
+		oidcProviders:   map[string]*oidc.Provider{},
+		resetSends:      make(chan struct{}, maxPasswordResetSends),
+	}
+}
+
+func testToken(t *testing.T, keys *auth.KeySet, role string, claims auth.Claims) string {
+	t.Helper()
+	claims.Role = role
+	token, err := auth.MakeJWTWithClaims(uuid.New(), claims, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error creating token: %v", err)
+	}
+	return token
+}
+
+type problemCase struct {
+	name      string
+	method    string
+	path      string
+	token     string
+	header    http.Header
+	body      string
+	status    int
+	code      string
+	fields    []string
+	challenge string
+}
+
+func TestErrorContract(t *testing.T) {
+	cfg := testConfig(t)
+	handler := cfg.routes()
+
+	user := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{})
+	moderator := testToken(t, cfg.JWTKeys, auth.RoleModerator, auth.Claims{})
+	admin := testToken(t, cfg.JWTKeys, auth.RoleAdmin, auth.Claims{})
+	readOnly := testToken(t, cfg.JWTKeys, auth.RoleUser, auth.Claims{ClientID: uuid.NewString(), Scope: auth.ScopeChirpsRead})
+	userID := uuid.NewString()
+
+	cases := []problemCase{
+		{name: "no token", method: "GET", path: "/api/sessions", status: 401, code: codeUnauthenticated, challenge: `Bearer realm="chirpy"`},
+		{name: "malformed header", method: "POST", path: "/api/chirps", header: http.Header{"Authorization": {"Basic abc"}}, status: 401, code: codeUnauthenticated, challenge: `Bearer realm="chirpy"`},
+		{name: "bad token", method: "GET", path: "/api/timeline", token: "not-a-jwt", status: 401, code: codeInvalidToken, challenge: `error="invalid_token"`},
+		{name: "bad token on public route", method: "GET", path: "/api/chirps", token: "not-a-jwt", status: 401, code: codeInvalidToken},
+		{name: "delegated token on first-party route", method: "GET", path: "/api/sessions", token: readOnly, status: 403, code: codeInsufficientScope, challenge: `error="insufficient_scope"`},
+		{name: "missing scope", method: "POST", path: "/api/chirps", token: readOnly, body: `{"body":"hi"}`, status: 403, code: codeInsufficientScope, challenge: `scope="chirps:write"`},
+		{name: "role too low", method: "GET", path: "/admin/metrics", token: user, status: 403, code: codeForbidden},
+		{name: "moderator on admin route", method: "POST", path: "/admin/users/" + userID + "/unlock", token: moderator, status: 403, code: codeForbidden},
+		{name: "token body not JSON", method: "POST", path: "/api/tokens", token: user, body: "{", status: 400, code: codeInvalidBody},
+		{name: "token fields", method: "POST", path: "/api/tokens", token: user, body: `{}`, status: 400, code: codeValidationFailed, fields: []string{"name", "scopes"}},
+		{name: "oauth client fields", method: "POST", path: "/api/oauth/clients", token: user, body: `{"scope":"admin"}`, status: 400, code: codeValidationFailed, fields: []string{"client_name", "redirect_uris", "scope"}},
+		{name: "signup body not JSON", method: "POST", path: "/api/users", body: "nope", status: 400, code: codeInvalidBody},
+		{name: "signup without email", method: "POST", path: "/api/users", body: `{"password":"correct horse battery"}`, status: 400, code: codeValidationFailed, fields: []string{"email"}},
+		{name: "signup short password", method: "POST", path: "/api/users", body: `{"email":"a@example.com","password":"short"}`, status: 400, code: codeValidationFailed, fields: []string{"password"}},
+		{name: "login without email", method: "POST", path: "/api/login", body: `{"password":"x"}`, status: 400, code: codeValidationFailed, fields: []string{"email"}},
+		{name: "update user fields", method: "PUT", path: "/api/users", token: user, body: `{"email":"nope"}`, status: 400, code: codeValidationFailed, fields: []string{"email", "password"}},
+		{name: "password reset without email", method: "POST", path: "/api/password-reset/request", body: `{}`, status: 400, code: codeValidationFailed, fields: []string{"email"}},
+		{name: "page parameters", method: "GET", path: "/api/chirps?limit=0", status: 400, code: codeValidationFailed, fields: []string{"limit"}},
+		{name: "chirp listing author", method: "GET", path: "/api/chirps?author_id=nope", status: 400, code: codeValidationFailed, fields: []string{"author_id"}},
+		{name: "search parameters", method: "GET", path: "/api/chirps/search?limit=0&offset=-1&since=yesterday", status: 400, code: codeValidationFailed, fields: []string{"q", "limit", "offset", "since"}},
+		{name: "trending parameters", method: "GET", path: "/api/tags/trending?window=-1h&limit=nope", status: 400, code: codeValidationFailed, fields: []string{"window", "limit"}},
+		{name: "report without reason", method: "POST", path: "/api/chirps/" + uuid.NewString() + "/report", token: user, body: `{}`, status: 400, code: codeValidationFailed, fields: []string{"reason"}},
+		{name: "report queue status", method: "GET", path: "/admin/reports?status=closed", token: moderator, status: 400, code: codeValidationFailed, fields: []string{"status"}},
+		{name: "moderation word fields", method: "POST", path: "/admin/moderation/words", token: admin, body: `{"word":"..."}`, status: 400, code: codeValidationFailed, fields: []string{"word"}},
+		{name: "moderation word action", method: "PUT", path: "/admin/moderation/words/" + uuid.NewString(), token: admin, body: `{"action":"ban"}`, status: 400, code: codeValidationFailed, fields: []string{"action"}},
+		{name: "role", method: "PUT", path: "/admin/users/" + userID + "/role", token: admin, body: `{"role":"owner"}`, status: 400, code: codeValidationFailed, fields: []string{"role"}},
+		{name: "authorization request client", method: "GET", path: "/api/oauth/authorize?client_id=nope", token: user, status: 400, code: codeValidationFailed, fields: []string{"client_id"}},
+		{name: "login database down", method: "POST", path: "/api/login", body: `{"email":"a@example.com","password":"x"}`, status: 500, code: codeInternal},
+		{name: "invalid chirp ID", method: "DELETE", path: "/api/chirps/not-a-uuid", token: user, status: 400, code: codeBadRequest},
+		{name: "invalid user ID", method: "GET", path: "/api/users/not-a-uuid/followers", status: 400, code: codeBadRequest},
+		{name: "unknown identity provider", method: "GET", path: "/api/oidc/nope/login", status: 404, code: codeNotFound},
+		{name: "unsupported grant", method: "POST", path: "/api/oauth/token", header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}, body: "grant_type=password", status: 400, code: "unsupported_grant_type"},
+		{name: "webhook without key", method: "POST", path: "/api/polka/webhooks", body: `{"event":"user.upgraded"}`, status: 401, code: codeInvalidAPIKey},
+		{name: "webhook body not JSON", method: "POST", path: "/api/polka/webhooks", body: "{", status: 400, code: codeInvalidBody},
+		{name: "reset outside dev", method: "POST", path: "/admin/reset", token: admin, status: 403, code: codeForbidden},
+		{name: "unknown route", method: "GET", path: "/api/nope", status: 404, code: codeNotFound},
+		{name: "wrong method", method: "PATCH", path: "/api/login", status: 405, code: codeMethodNotAllowed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			p := decodeProblem(t, rec)
+			if rec.Code != tc.status || p.Status != tc.status {
+				t.Errorf("status = %d (body %d), want %d", rec.Code, p.Status, tc.status)
+			}
+			if p.Code != tc.code {
+				t.Errorf("code = %q, want %q", p.Code, tc.code)
+			}
+			if p.Title != http.StatusText(tc.status) {
+				t.Errorf("title = %q, want %q", p.Title, http.StatusText(tc.status))
+			}
+			if p.Type != "about:blank" {
+				t.Errorf("type = %q, want about:blank", p.Type)
+			}
+			var fields []string
+			for _, e := range p.Errors {
+				if e.Code == "" || e.Message == "" {
+					t.Errorf("field error %+v lacks a code or message", e)
+				}
+				if len(fields) == 0 || fields[len(fields)-1] != e.Field {
+					fields = append(fields, e.Field)
+				}
+			}
+			if strings.Join(fields, ",") != strings.Join(tc.fields, ",") {
+				t.Errorf("fields = %v, want %v", fields, tc.fields)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, tc.challenge) {
+				t.Errorf("WWW-Authenticate = %q, want it to contain %q", got, tc.challenge)
+			}
+		})
+	}
+}
+
+func TestErrorContractDetails(t *testing.T) {
+	cfg := testConfig(t)
+	handler := cfg.routes()
+
+	t.Run("request ID is echoed", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/sessions", nil)
+		req.Header.Set(requestIDHeader, "trace-abc.123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get(requestIDHeader); got != "trace-abc.123" {
+			t.Errorf("X-Request-ID = %q, want trace-abc.123", got)
+		}
+		if p := decodeProblem(t, rec); p.RequestID != "trace-abc.123" {
+			t.Errorf("request_id = %q, want trace-abc.123", p.RequestID)
+		}
+	})
+
+	t.Run("bad request ID is replaced", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/sessions", nil)
+		req.Header.Set(requestIDHeader, "has spaces\nand newlines")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if _, err := uuid.Parse(rec.Header().Get(requestIDHeader)); err != nil {
+			t.Errorf("X-Request-ID = %q, want a generated UUID", rec.Header().Get(requestIDHeader))
+		}
+	})
+
+	t.Run("method not allowed lists methods", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("PATCH", "/api/login", nil))
+		decodeProblem(t, rec)
+		if got := rec.Header().Get("Allow"); !strings.Contains(got, "POST") {
+			t.Errorf("Allow = %q, want it to contain POST", got)
+		}
+	})
+
+	t.Run("oauth errors keep RFC 6749 members", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/oauth/token", strings.NewReader("grant_type=password"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var body struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("unexpected error decoding body: %v", err)
+		}
+		if body.Error != "unsupported_grant_type" || body.ErrorDescription == "" {
+			t.Errorf("body = %+v, want error and error_description", body)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("Cache-Control = %q, want no-store", got)
+		}
+	})
+
+	t.Run("login throttling sets Retry-After", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rec.Header().Set(requestIDHeader, "abc")
+		respondTooManyAttempts(rec, 1500*time.Millisecond)
+		if p := decodeProblem(t, rec); p.Code != codeTooManyRequests || p.RequestID != "abc" {
+			t.Errorf("problem = %+v, want too_many_requests with request ID", p)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "2" {
+			t.Errorf("Retry-After = %q, want 2", got)
+		}
+	})
+}
+
+// decodeProblem checks the response is a problem document carrying the
+// response's request ID, and returns it.
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) problem {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q, want application/problem+json (body %q)", ct, rec.Body.String())
+	}
+	var p problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("unexpected error decoding problem: %v", err)
+	}
+	if id := rec.Header().Get(requestIDHeader); id == "" || p.RequestID != id {
+		t.Errorf("request_id = %q, want X-Request-ID %q", p.RequestID, id)
+	}
+	return p
+}
@@ -4,11 +4,11 @@ import "net/http"
 
 func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
 	if cfg.platform != "dev" {
-		http.Error(w, "forbidden", http.StatusForbidden)
+		respondWithError(w, http.StatusForbidden, "Reset is only allowed in dev", nil)
 		return
 	}
 	if err := cfg.queries.ResetUsers(r.Context()); err != nil {
-		http.Error(w, "Failed to reset users", http.StatusInternalServerError)
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset users", err)
 		return
 	}
 	cfg.fileserverHits.Store(0)
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+)
+
+const filepathRoot = "."
+
+// routes builds the server's handler: every route, behind the request ID
+// middleware, with problem responses for paths and methods no route serves.
+func (cfg *apiConfig) routes() http.Handler {
+	// authed routes take only the user's own tokens; scoped ones also take
+	// delegated tokens granting the scope. maybeAuthed routes are public but
+	// personalise their output for a caller who may read chirps.
+	authed := func(h http.HandlerFunc) http.Handler {
+		return cfg.middlewareAuth(authPolicy{}, h)
+	}
+	scoped := func(scope string, h http.HandlerFunc) http.Handler {
+		return cfg.middlewareAuth(authPolicy{scope: scope}, h)
+	}
+	maybeAuthed := func(h http.HandlerFunc) http.Handler {
+		return cfg.middlewareAuth(authPolicy{scope: auth.ScopeChirpsRead, optional: true}, h)
+	}
+	anyToken := func(h http.HandlerFunc) http.Handler {
+		return cfg.middlewareAuth(authPolicy{anyToken: true}, h)
+	}
+	// /admin routes are wrapped so only moderators or admins reach them
+	admin := func(h http.HandlerFunc) http.Handler {
+		return cfg.middlewareRequireRole(auth.RoleAdmin, h)
+	}
+	moderator := func(h http.HandlerFunc) http.Handler {
+		return cfg.middlewareRequireRole(auth.RoleModerator, h)
+	}
+
+	mux := http.NewServeMux()
+	fsHandler := cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
+	mux.Handle("/app/", fsHandler)
+
+	//Get
+	mux.HandleFunc("GET /api/healthz", handlerReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.handlerJWKS)
+	mux.Handle("GET /api/chirps", maybeAuthed(cfg.handlerGetAllChirps))
+	mux.Handle("GET /api/chirps/search", maybeAuthed(cfg.handlerSearchChirps))
+	mux.Handle("GET /api/chirps/{chirpID}", maybeAuthed(cfg.handlerGetChirp))
+	mux.Handle("GET /api/chirps/{chirpID}/thread", maybeAuthed(cfg.handlerGetThread))
+	mux.Handle("GET /api/chirps/{chirpID}/revisions", maybeAuthed(cfg.handlerGetChirpRevisions))
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.handlerGetFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.handlerGetFollowing)
+	mux.Handle("GET /api/timeline", scoped(auth.ScopeChirpsRead, cfg.handlerGetTimeline))
+	mux.HandleFunc("GET /api/tags/trending", cfg.handlerGetTrendingTags)
+	mux.Handle("GET /api/tags/{tag}/chirps", maybeAuthed(cfg.handlerGetTagChirps))
+	mux.Handle("GET /api/users/me/mentions", scoped(auth.ScopeChirpsRead, cfg.handlerGetMyMentions))
+	mux.Handle("GET /api/sessions", authed(cfg.handlerListSessions))
+	mux.HandleFunc("GET /api/oidc/{provider}/login", cfg.handlerOIDCLogin)
+	mux.HandleFunc("GET /api/oidc/{provider}/callback", cfg.handlerOIDCCallback)
+	mux.Handle("GET /api/users/me", scoped(auth.ScopeProfile, cfg.handlerGetMe))
+	mux.Handle("GET /api/oauth/clients", authed(cfg.handlerListOAuthClients))
+	mux.Handle("GET /api/oauth/authorize", authed(cfg.handlerGetAuthorize))
+	mux.Handle("GET /api/tokens", authed(cfg.handlerListPersonalTokens))
+	mux.Handle("GET /admin/metrics", admin(cfg.handlerMetrics))
+	mux.Handle("GET /admin/moderation/words", admin(cfg.handlerListModerationWords))
+	mux.Handle("GET /admin/reports", moderator(cfg.handlerListReports))
+
+	//Post
+	mux.Handle("POST /api/chirps", scoped(auth.ScopeChirpsWrite, cfg.handlerCreateChirp))
+	mux.HandleFunc("POST /api/users", cfg.handlerCreateUser)
+	mux.HandleFunc("POST /api/login", cfg.handlerLogin)
+	mux.HandleFunc("POST /api/login/mfa", cfg.handlerLoginMFA)
+	mux.Handle("POST /api/mfa/totp/enroll", authed(cfg.handlerEnrollTOTP))
+	mux.Handle("POST /api/mfa/totp/confirm", authed(cfg.handlerConfirmTOTP))
+	mux.Handle("POST /api/mfa/totp/disable", authed(cfg.handlerDisableTOTP))
+	mux.Handle("POST /admin/reset", admin(cfg.handlerReset))
+	mux.Handle("POST /admin/moderation/words", admin(cfg.handlerCreateModerationWord))
+	mux.Handle("POST /api/chirps/{chirpID}/report", authed(cfg.handlerReportChirp))
+	mux.Handle("POST /admin/reports/{reportID}/dismiss", moderator(cfg.handlerDismissReport))
+	mux.Handle("POST /admin/chirps/{chirpID}/hide", moderator(cfg.handlerHideChirp))
+	mux.Handle("POST /admin/chirps/{chirpID}/restore", moderator(cfg.handlerRestoreChirp))
+	mux.Handle("POST /admin/users/{userID}/suspend", moderator(cfg.handlerSuspendUser))
+	mux.Handle("POST /admin/users/{userID}/unsuspend", moderator(cfg.handlerUnsuspendUser))
+	mux.Handle("POST /admin/users/{userID}/unlock", admin(cfg.handlerUnlockUser))
+	mux.HandleFunc("POST /api/refresh", cfg.handlerRefresh)
+	mux.HandleFunc("POST /api/revoke", cfg.handlerRevoke)
+	mux.Handle("POST /api/logout", anyToken(cfg.handlerLogout))
+	mux.HandleFunc("POST /api/users/verify", cfg.handlerVerifyEmail)
+	mux.Handle("POST /api/users/verify/resend", authed(cfg.handlerResendVerification))
+	mux.HandleFunc("POST /api/password-reset/request", cfg.handlerRequestPasswordReset)
+	mux.HandleFunc("POST /api/password-reset/confirm", cfg.handlerConfirmPasswordReset)
+	mux.Handle("POST /api/sessions/revoke-others", authed(cfg.handlerRevokeOtherSessions))
+	mux.Handle("POST /api/oauth/clients", authed(cfg.handlerRegisterOAuthClient))
+	mux.Handle("POST /api/oauth/authorize", authed(cfg.handlerPostAuthorize))
+	mux.HandleFunc("POST /api/oauth/token", cfg.handlerOAuthToken)
+	mux.Handle("POST /api/tokens", authed(cfg.handlerCreatePersonalToken))
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.handler_webhook)
+	mux.Handle("POST /api/users/{userID}/follow", authed(cfg.handlerFollowUser))
+	mux.Handle("POST /api/chirps/{chirpID}/like", authed(cfg.handlerLikeChirp))
+
+	//Put
+	mux.Handle("PUT /api/users", authed(cfg.handlerUpdateUser))
+	mux.Handle("PUT /api/chirps/{chirpID}", scoped(auth.ScopeChirpsWrite, cfg.handlerUpdateChirp))
+	mux.Handle("PUT /admin/moderation/words/{wordID}", admin(cfg.handlerUpdateModerationWord))
+	mux.Handle("PUT /admin/users/{userID}/role", admin(cfg.handlerSetUserRole))
+
+	//Delete
+	mux.Handle("DELETE /api/chirps/{chirpID}", scoped(auth.ScopeChirpsWrite, cfg.handlerDeleteChirp))
+	mux.Handle("DELETE /api/users/{userID}/follow", authed(cfg.handlerUnfollowUser))
+	mux.Handle("DELETE /api/chirps/{chirpID}/like", authed(cfg.handlerUnlikeChirp))
+	mux.Handle("DELETE /api/sessions/{sessionID}", authed(cfg.handlerRevokeSession))
+	mux.Handle("DELETE /api/oauth/clients/{clientID}", authed(cfg.handlerDeleteOAuthClient))
+	mux.Handle("DELETE /api/tokens/{tokenID}", authed(cfg.handlerRevokePersonalToken))
+	mux.Handle("DELETE /admin/moderation/words/{wordID}", admin(cfg.handlerDeleteModerationWord))
+	mux.Handle("DELETE /admin/chirps/{chirpID}", moderator(cfg.handlerAdminDeleteChirp))
+
+	return middlewareRequestID(problemFallback(mux))
+}
+
+// problemFallback answers requests the mux has no route for with a problem
+// instead of its plain text 404 or 405.
+func problemFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		// let the mux's own handler pick the status and Allow header
+		rec := &statusRecorder{header: http.Header{}}
+		h.ServeHTTP(rec, r)
+		if rec.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		if rec.status != http.StatusNotFound {
+			// a redirect, e.g. to add a trailing slash
+			h.ServeHTTP(w, r)
+			return
+		}
+		respondWithError(w, http.StatusNotFound, "No route for "+r.URL.Path, nil)
+	})
+}
+
+// statusRecorder captures a handler's status and headers and drops its body.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header { return s.header }
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Security event names stored in security_events.event.
+const (
+	eventRefreshTokenReuse = "refresh_token_reuse"
+	eventPasswordReset     = "password_reset"
+)
+
+// clientIP returns the host part of the request's remote address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// recordSecurityEvent writes an event to the log and to security_events.
+// userID may be uuid.Nil when the event is not tied to an account. q should
+// be bound to the caller's transaction when there is one.
+func recordSecurityEvent(ctx context.Context, q *database.Queries, r *http.Request, userID uuid.UUID, event, detail string) error {
+	ip := clientIP(r)
+	log.Printf("security: %s user=%s ip=%s %s", event, userID, ip, detail)
+	return q.CreateSecurityEvent(ctx, database.CreateSecurityEventParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		Event:  event,
+		Ip:     ip,
+		Detail: detail,
+	})
+}
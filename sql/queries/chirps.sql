@@ -1,24 +1,155 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, body, user_id)
- VALUES ($1, $2, $3)
- RETURNING *;
+INSERT INTO chirps (id, body, user_id, parent_chirp_id)
+ VALUES ($1, $2, $3, $4)
+ RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at;
 
 -- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
  FROM chirps
+ WHERE deleted_at IS NULL
+   AND hidden_at IS NULL
  ORDER BY created_at ASC, id ASC;
 
 -- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
  FROM chirps
  WHERE id = $1;
 
+-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
+ FROM chirps
+ WHERE id = $1
+ FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at;
+
+-- name: HideChirp :execrows
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1
+  AND hidden_at IS NULL;
+
+-- name: RestoreChirp :execrows
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1
+  AND hidden_at IS NOT NULL;
+
 -- name: RemoveChirp :exec
 DELETE FROM chirps
  WHERE id = $1;
 
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
 -- name: GetChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
  FROM chirps
  WHERE user_id = $1
- ORDER BY created_at ASC, id ASC;
\ No newline at end of file
+   AND deleted_at IS NULL
+   AND hidden_at IS NULL
+ ORDER BY created_at ASC, id ASC;
+
+-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
+ FROM chirps
+ WHERE deleted_at IS NULL
+   AND hidden_at IS NULL
+   AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+   AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ ORDER BY created_at ASC, id ASC
+ LIMIT sqlc.arg('page_size');
+
+-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at
+ FROM chirps
+ WHERE deleted_at IS NULL
+   AND hidden_at IS NULL
+   AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+   AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ ORDER BY created_at DESC, id DESC
+ LIMIT sqlc.arg('page_size');
+
+-- name: CountChirpReplies :one
+SELECT COUNT(*)
+ FROM chirps
+ WHERE parent_chirp_id = sqlc.arg('chirp_id')::uuid;
+
+-- name: CountRepliesForChirps :many
+SELECT parent_chirp_id, COUNT(*) AS reply_count
+ FROM chirps
+ WHERE parent_chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+   AND deleted_at IS NULL
+   AND hidden_at IS NULL
+ GROUP BY parent_chirp_id;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, parent_chirp_id, depth) AS (
+    SELECT c.id, c.parent_chirp_id, 0
+     FROM chirps c
+     WHERE c.id = sqlc.arg('chirp_id')::uuid
+    UNION ALL
+    SELECT p.id, p.parent_chirp_id, a.depth + 1
+     FROM chirps p
+     JOIN ancestors a ON p.id = a.parent_chirp_id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at
+ FROM chirps
+ JOIN ancestors ON ancestors.id = chirps.id
+ WHERE ancestors.depth > 0
+ ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants (id) AS (
+    SELECT c.id
+     FROM chirps c
+     WHERE c.parent_chirp_id = sqlc.arg('chirp_id')::uuid
+    UNION ALL
+    SELECT c.id
+     FROM chirps c
+     JOIN descendants d ON c.parent_chirp_id = d.id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at
+ FROM chirps
+ JOIN descendants ON descendants.id = chirps.id
+ ORDER BY chirps.created_at ASC, chirps.id ASC;
+
+-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at
+ FROM chirps
+ JOIN follows ON follows.followee_id = chirps.user_id
+ WHERE follows.follower_id = sqlc.arg('user_id')::uuid
+   AND chirps.deleted_at IS NULL
+   AND chirps.hidden_at IS NULL
+   AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ ORDER BY chirps.created_at DESC, chirps.id DESC
+ LIMIT sqlc.arg('page_size');
+
+-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at,
+       ts_rank(to_tsvector('english', chirps.body), query)::real AS rank,
+       ts_headline('english',
+                   replace(replace(replace(replace(replace(chirps.body,
+                       '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+                   query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')::text AS snippet
+ FROM chirps, websearch_to_tsquery('english', sqlc.arg('query')::text) AS query
+ WHERE to_tsvector('english', chirps.body) @@ query
+   AND chirps.deleted_at IS NULL
+   AND chirps.hidden_at IS NULL
+   AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+   AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+   AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+ ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+ LIMIT sqlc.arg('page_size') OFFSET sqlc.arg('page_offset');
\ No newline at end of file
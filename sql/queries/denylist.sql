@@ -0,0 +1,25 @@
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (jti, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: ListRevokedAccessTokens :many
+SELECT jti FROM revoked_access_tokens
+WHERE expires_at > NOW();
+
+-- name: DeleteExpiredRevokedAccessTokens :exec
+DELETE FROM revoked_access_tokens
+WHERE expires_at <= NOW();
+
+-- name: RevokeSessionAccessTokens :exec
+INSERT INTO revoked_sessions (family_id, user_id, expires_at)
+SELECT unnest(sqlc.arg('family_ids')::uuid[]), sqlc.arg('user_id')::uuid, sqlc.arg('expires_at')::timestamp
+ON CONFLICT (family_id) DO NOTHING;
+
+-- name: ListRevokedSessions :many
+SELECT family_id FROM revoked_sessions
+WHERE expires_at > NOW();
+
+-- name: DeleteExpiredRevokedSessions :exec
+DELETE FROM revoked_sessions
+WHERE expires_at <= NOW();
\ No newline at end of file
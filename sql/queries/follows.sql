@@ -0,0 +1,29 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1
+  AND followee_id = $2;
+
+-- name: ListFollowers :many
+SELECT users.id, follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = sqlc.arg('user_id')::uuid
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (follows.created_at, users.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListFollowing :many
+SELECT users.id, follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg('user_id')::uuid
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (follows.created_at, users.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('page_size');
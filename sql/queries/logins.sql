@@ -0,0 +1,38 @@
+-- name: RecordLoginAttempt :exec
+INSERT INTO login_attempts (id, email, user_id, ip, outcome)
+VALUES (gen_random_uuid(), $1, $2, $3, $4);
+
+-- name: GetRecentFailedLoginsByIP :one
+SELECT COUNT(*) AS failures,
+       COALESCE(MAX(created_at), 'epoch')::timestamp AS last_failure
+FROM login_attempts
+WHERE ip = $1
+  AND outcome = 'failure'
+  AND created_at > $2;
+
+-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_count = failed_login_count + 1,
+    last_failed_login_at = NOW(),
+    locked_until = CASE
+        WHEN failed_login_count + 1 >= sqlc.arg('lockout_threshold')::int THEN sqlc.arg('locked_until')::timestamp
+        ELSE locked_until
+    END
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_count = 0,
+    last_failed_login_at = NULL,
+    locked_until = NULL
+WHERE id = $1;
+
+-- name: UnlockUser :one
+UPDATE users
+SET failed_login_count = 0,
+    last_failed_login_at = NULL,
+    locked_until = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,62 @@
+-- name: SetPendingTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2,
+    totp_enabled_at = NULL,
+    totp_last_counter = 0,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(),
+    totp_last_counter = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: AdvanceTOTPCounter :execrows
+UPDATE users
+SET totp_last_counter = $2
+WHERE id = $1
+  AND totp_last_counter < $2;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_counter = 0,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: AddRecoveryCodes :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+SELECT sqlc.arg('user_id')::uuid, unnest(sqlc.arg('code_hashes')::text[]);
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL;
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (token_hash, user_id, expires_at, access_ttl_seconds)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetMFAChallengeForUpdate :one
+SELECT * FROM mfa_challenges
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: RecordMFAChallengeAttempt :exec
+UPDATE mfa_challenges
+SET attempts = attempts + 1
+WHERE token_hash = $1;
+
+-- name: UseMFAChallenge :exec
+UPDATE mfa_challenges
+SET used_at = NOW()
+WHERE token_hash = $1;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- name: ListModerationWords :many
+SELECT * FROM moderation_words
+ORDER BY word ASC;
+
+-- name: CreateModerationWord :one
+INSERT INTO moderation_words (id, created_at, updated_at, word, action)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+RETURNING *;
+
+-- name: UpdateModerationWord :one
+UPDATE moderation_words
+SET action = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteModerationWord :execrows
+DELETE FROM moderation_words
+WHERE id = $1;
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, actor_id, action, chirp_id, user_id, report_id, note)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6);
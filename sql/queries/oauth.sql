@@ -0,0 +1,32 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, owner_id, name, secret_hash, redirect_uris, scopes)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1;
+
+-- name: ListOAuthClientsByOwner :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = $1
+  AND owner_id = $2;
+
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, redirect_uri_sent)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetOAuthAuthorizationCodeForUpdate :one
+SELECT * FROM oauth_authorization_codes
+WHERE code_hash = $1
+FOR UPDATE;
+
+-- name: UseOAuthAuthorizationCode :exec
+UPDATE oauth_authorization_codes
+SET used_at = NOW()
+WHERE code_hash = $1;
\ No newline at end of file
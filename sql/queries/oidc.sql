@@ -0,0 +1,36 @@
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: TakeOIDCLoginState :one
+DELETE FROM oidc_login_states
+WHERE state_hash = $1
+RETURNING *;
+
+-- name: DeleteExpiredOIDCLoginStates :exec
+DELETE FROM oidc_login_states
+WHERE expires_at < NOW();
+
+-- name: GetUserByIdentity :one
+SELECT users.*
+FROM users
+JOIN user_identities ON user_identities.user_id = users.id
+WHERE user_identities.provider = $1
+  AND user_identities.subject = $2;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4);
+
+-- name: CreateExternalUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red, email_verified_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    '',
+    FALSE,
+    $2
+)
+RETURNING *;
\ No newline at end of file
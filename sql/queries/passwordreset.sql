@@ -0,0 +1,32 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: GetPasswordResetTokenForUpdate :one
+SELECT * FROM password_reset_tokens
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: UsePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = $1
+  AND used_at IS NULL;
+
+-- name: RecordPasswordResetRequest :exec
+INSERT INTO password_reset_requests (id, email, ip)
+VALUES (gen_random_uuid(), $1, $2);
+
+-- name: GetRecentPasswordResetRequestsByEmail :one
+SELECT COUNT(*) AS requests,
+       COALESCE(MAX(created_at), 'epoch')::timestamp AS last_request
+FROM password_reset_requests
+WHERE email = $1
+  AND created_at > $2;
+
+-- name: GetRecentPasswordResetRequestsByIP :one
+SELECT COUNT(*) AS requests,
+       COALESCE(MAX(created_at), 'epoch')::timestamp AS last_request
+FROM password_reset_requests
+WHERE ip = $1
+  AND created_at > $2;
\ No newline at end of file
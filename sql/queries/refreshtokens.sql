@@ -1,13 +1,13 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, family_id, last_used_at, user_agent, ip)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetUserFromRefreshToken :one
 SELECT users.*
 FROM users
 JOIN refresh_tokens ON refresh_tokens.user_id = users.id
-WHERE refresh_tokens.token = $1
+WHERE refresh_tokens.token_hash = $1
   AND refresh_tokens.revoked_at IS NULL
   AND refresh_tokens.expires_at > NOW();
 
@@ -16,4 +16,59 @@ WHERE refresh_tokens.token = $1
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1;
+WHERE token_hash = $1;
+
+-- name: GetRefreshTokenForUpdate :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: MarkRefreshTokenRotated :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW(),
+    replaced_by_hash = $2
+WHERE token_hash = $1;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+  AND revoked_at IS NULL;
+
+-- name: ListUserSessions :many
+SELECT refresh_tokens.family_id,
+       (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = refresh_tokens.family_id)::timestamp AS started_at,
+       refresh_tokens.last_used_at,
+       refresh_tokens.user_agent,
+       refresh_tokens.ip,
+       refresh_tokens.expires_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+  AND refresh_tokens.expires_at > NOW()
+ORDER BY COALESCE(refresh_tokens.last_used_at, refresh_tokens.created_at) DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND family_id = $2
+  AND revoked_at IS NULL;
+
+-- name: RevokeOtherUserSessions :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND family_id <> $2
+  AND revoked_at IS NULL;
+
+-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+  AND revoked_at IS NULL;
\ No newline at end of file
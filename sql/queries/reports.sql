@@ -0,0 +1,34 @@
+-- name: CreateChirpReport :one
+INSERT INTO chirp_reports (id, created_at, chirp_id, reason, reporter_id)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING *;
+
+-- name: GetChirpReport :one
+SELECT * FROM chirp_reports
+WHERE id = $1;
+
+-- name: ListChirpReports :many
+SELECT chirp_reports.id, chirp_reports.created_at, chirp_reports.chirp_id, chirp_reports.reason,
+       chirp_reports.reporter_id, chirp_reports.status, chirp_reports.resolved_at,
+       chirps.body AS chirp_body, chirps.user_id AS author_id, chirps.hidden_at AS chirp_hidden_at
+FROM chirp_reports
+JOIN chirps ON chirps.id = chirp_reports.chirp_id
+WHERE chirp_reports.status = sqlc.arg('status')::text
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirp_reports.created_at, chirp_reports.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirp_reports.created_at ASC, chirp_reports.id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: ResolveChirpReports :exec
+UPDATE chirp_reports
+SET status = 'resolved',
+    resolved_at = NOW()
+WHERE chirp_id = $1
+  AND status = 'open';
+
+-- name: DismissChirpReport :execrows
+UPDATE chirp_reports
+SET status = 'dismissed',
+    resolved_at = NOW()
+WHERE id = $1
+  AND status = 'open';
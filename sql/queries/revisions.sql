@@ -0,0 +1,14 @@
+-- name: CreateChirpRevision :exec
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at)
+SELECT gen_random_uuid(), chirps.id, chirps.body, chirps.updated_at
+FROM chirps
+WHERE chirps.id = $1;
+
+-- name: ListChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at ASC, id ASC;
+
+-- name: DeleteChirpRevisions :exec
+DELETE FROM chirp_revisions
+WHERE chirp_id = $1;
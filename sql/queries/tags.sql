@@ -0,0 +1,56 @@
+-- name: AddChirpTags :exec
+INSERT INTO chirp_tags (chirp_id, tag)
+SELECT sqlc.arg('chirp_id')::uuid, unnest(sqlc.arg('tags')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteStaleChirpTags :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = sqlc.arg('chirp_id')::uuid
+  AND NOT (tag = ANY(sqlc.arg('keep')::text[]));
+
+-- name: AddChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT sqlc.arg('chirp_id')::uuid, users.id
+FROM users
+WHERE users.email = ANY(sqlc.arg('emails')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteStaleChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = sqlc.arg('chirp_id')::uuid
+  AND user_id NOT IN (SELECT users.id FROM users WHERE users.email = ANY(sqlc.arg('keep_emails')::text[]));
+
+-- name: ListChirpsByTag :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at
+FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+WHERE chirp_tags.tag = sqlc.arg('tag')::text
+  AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListMentionsForUser :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.hidden_at
+FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')::uuid
+  AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: GetTrendingTags :many
+SELECT chirp_tags.tag, COUNT(*) AS chirp_count
+FROM chirp_tags
+JOIN chirps ON chirps.id = chirp_tags.chirp_id
+WHERE chirp_tags.created_at >= NOW() - make_interval(secs => sqlc.arg('window_seconds')::float8)
+  AND chirps.hidden_at IS NULL
+  AND chirps.deleted_at IS NULL
+GROUP BY chirp_tags.tag
+ORDER BY chirp_count DESC, chirp_tags.tag ASC
+LIMIT sqlc.arg('page_size');
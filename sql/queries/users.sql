@@ -11,14 +11,21 @@ VALUES (
 RETURNING *;
 
 -- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT *
  FROM users
  WHERE email = $1;
 
+-- name: GetUserByEmailForUpdate :one
+SELECT *
+ FROM users
+ WHERE email = $1
+ FOR UPDATE;
+
 -- name: UpdateUser :one
 UPDATE users
 SET email = $2,
     hashed_password = $3,
+    email_verified_at = CASE WHEN email = $2 THEN email_verified_at END,
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
@@ -27,4 +34,63 @@ RETURNING *;
 UPDATE users
 SET is_chirpy_red = TRUE
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT *
+ FROM users
+ WHERE id = $1;
+
+-- name: GetUserByIDForUpdate :one
+SELECT *
+ FROM users
+ WHERE id = $1
+ FOR UPDATE;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = NOW(),
+    token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET token_version = CASE WHEN role = $2 THEN token_version ELSE token_version + 1 END,
+    role = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: BootstrapAdmin :execrows
+UPDATE users
+SET role = 'admin',
+    updated_at = NOW()
+WHERE email = $1
+  AND email_verified_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin');
+
+-- name: GetUserTokenVersion :one
+SELECT token_version FROM users
+WHERE id = $1;
+
+-- name: BumpUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING token_version;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1;
\ No newline at end of file
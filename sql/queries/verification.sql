@@ -0,0 +1,22 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, user_id, email, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetEmailVerificationTokenForUpdate :one
+SELECT * FROM email_verification_tokens
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: UseEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE user_id = $1
+  AND used_at IS NULL;
+
+-- name: MarkEmailVerified :one
+UPDATE users
+SET email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND email = $2
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE chirps
+ ADD COLUMN parent_chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_parent_chirp_id_idx ON chirps (parent_chirp_id);
+
+-- +goose Down
+DROP INDEX chirps_parent_chirp_id_idx;
+
+ALTER TABLE chirps
+ DROP COLUMN deleted_at,
+ DROP COLUMN parent_chirp_id;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE chirp_tags (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chirp_id, tag)
+);
+
+CREATE INDEX chirp_tags_tag_idx ON chirp_tags (tag, created_at);
+CREATE INDEX chirp_tags_created_at_idx ON chirp_tags (created_at);
+
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
+DROP TABLE chirp_tags;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE moderation_words (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    word TEXT NOT NULL UNIQUE,
+    action TEXT NOT NULL DEFAULT 'mask' CHECK (action IN ('mask', 'reject', 'flag'))
+);
+
+INSERT INTO moderation_words (id, word, action)
+VALUES
+    (gen_random_uuid(), 'kerfuffle', 'mask'),
+    (gen_random_uuid(), 'sharbert', 'mask'),
+    (gen_random_uuid(), 'fornax', 'mask');
+
+CREATE TABLE chirp_flags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL
+);
+
+CREATE INDEX chirp_flags_chirp_id_idx ON chirp_flags (chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE moderation_words;
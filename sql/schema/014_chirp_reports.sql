@@ -0,0 +1,51 @@
+-- +goose Up
+ALTER TABLE chirp_flags RENAME TO chirp_reports;
+ALTER INDEX chirp_flags_chirp_id_idx RENAME TO chirp_reports_chirp_id_idx;
+
+ALTER TABLE chirp_reports
+ ADD COLUMN reporter_id UUID REFERENCES users(id) ON DELETE SET NULL,
+ ADD COLUMN status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved', 'dismissed')),
+ ADD COLUMN resolved_at TIMESTAMP;
+
+CREATE INDEX chirp_reports_status_idx ON chirp_reports (status, created_at, id);
+CREATE UNIQUE INDEX chirp_reports_open_reporter_idx ON chirp_reports (chirp_id, reporter_id)
+ WHERE status = 'open';
+
+ALTER TABLE chirps
+ ADD COLUMN hidden_at TIMESTAMP;
+
+ALTER TABLE users
+ ADD COLUMN suspended_at TIMESTAMP;
+
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    chirp_id UUID,
+    user_id UUID,
+    report_id UUID,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX moderation_actions_created_at_idx ON moderation_actions (created_at);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+
+ALTER TABLE users
+ DROP COLUMN suspended_at;
+
+ALTER TABLE chirps
+ DROP COLUMN hidden_at;
+
+DROP INDEX chirp_reports_open_reporter_idx;
+DROP INDEX chirp_reports_status_idx;
+
+ALTER TABLE chirp_reports
+ DROP COLUMN resolved_at,
+ DROP COLUMN status,
+ DROP COLUMN reporter_id;
+
+ALTER INDEX chirp_reports_chirp_id_idx RENAME TO chirp_flags_chirp_id_idx;
+ALTER TABLE chirp_reports RENAME TO chirp_flags;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users
+ ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- +goose Down
+ALTER TABLE users
+ DROP COLUMN role;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ ADD COLUMN family_id UUID NOT NULL DEFAULT gen_random_uuid(),
+ ADD COLUMN replaced_by TEXT;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+CREATE TABLE security_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX security_events_user_id_idx ON security_events (user_id, created_at);
+
+-- +goose Down
+DROP TABLE security_events;
+DROP INDEX refresh_tokens_family_id_idx;
+
+ALTER TABLE refresh_tokens
+ DROP COLUMN replaced_by,
+ DROP COLUMN family_id;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ ADD COLUMN last_used_at TIMESTAMP,
+ ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ ADD COLUMN ip TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+ DROP COLUMN ip,
+ DROP COLUMN user_agent,
+ DROP COLUMN last_used_at;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE users
+ ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE revoked_access_tokens (
+    jti TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX revoked_access_tokens_expires_at_idx ON revoked_access_tokens (expires_at);
+
+-- +goose Down
+DROP TABLE revoked_access_tokens;
+
+ALTER TABLE users
+ DROP COLUMN token_version;
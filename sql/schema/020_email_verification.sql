@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE users
+ ADD COLUMN email_verified_at TIMESTAMP;
+
+-- accounts created before verification existed are trusted as they are
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE email_verification_tokens (
+    token_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+ DROP COLUMN email_verified_at;
@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE users
+ ADD COLUMN totp_secret TEXT,
+ ADD COLUMN totp_enabled_at TIMESTAMP,
+ ADD COLUMN totp_last_counter BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE mfa_recovery_codes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    used_at TIMESTAMP,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE mfa_challenges (
+    token_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    access_ttl_seconds INTEGER NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE mfa_challenges;
+DROP TABLE mfa_recovery_codes;
+
+ALTER TABLE users
+ DROP COLUMN totp_last_counter,
+ DROP COLUMN totp_enabled_at,
+ DROP COLUMN totp_secret;
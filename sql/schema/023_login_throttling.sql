@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE users
+ ADD COLUMN failed_login_count INTEGER NOT NULL DEFAULT 0,
+ ADD COLUMN last_failed_login_at TIMESTAMP,
+ ADD COLUMN locked_until TIMESTAMP;
+
+CREATE TABLE login_attempts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    email TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip TEXT NOT NULL,
+    outcome TEXT NOT NULL CHECK (outcome IN ('success', 'failure', 'throttled', 'locked'))
+);
+
+CREATE INDEX login_attempts_ip_idx ON login_attempts (ip, created_at);
+CREATE INDEX login_attempts_email_idx ON login_attempts (email, created_at);
+
+-- +goose Down
+DROP TABLE login_attempts;
+
+ALTER TABLE users
+ DROP COLUMN locked_until,
+ DROP COLUMN last_failed_login_at,
+ DROP COLUMN failed_login_count;
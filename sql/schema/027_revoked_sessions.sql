@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE revoked_sessions (
+    family_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX revoked_sessions_expires_at_idx ON revoked_sessions (expires_at);
+
+-- +goose Down
+DROP TABLE revoked_sessions;
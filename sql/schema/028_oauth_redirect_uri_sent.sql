@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE oauth_authorization_codes
+ ADD COLUMN redirect_uri_sent BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE oauth_authorization_codes
+ DROP COLUMN redirect_uri_sent;
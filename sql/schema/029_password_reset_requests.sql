@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE password_reset_requests (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    email TEXT NOT NULL,
+    ip TEXT NOT NULL
+);
+
+CREATE INDEX password_reset_requests_email_idx ON password_reset_requests (email, created_at);
+CREATE INDEX password_reset_requests_ip_idx ON password_reset_requests (ip, created_at);
+
+-- +goose Down
+DROP TABLE password_reset_requests;
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/auth"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// revocationTTL bounds how long an instance trusts its cached denylist and
+// token versions, so revocations made through another instance take effect
+// within that window.
+const revocationTTL = 30 * time.Second
+
+// sessionRevocationTTL is how long a revoked session's access tokens stay
+// denied. It covers the longest-lived access token a login issues.
+const sessionRevocationTTL = time.Hour
+
+type cachedTokenVersion struct {
+	version  int32
+	loadedAt time.Time
+}
+
+// tokenRevocations implements auth.RevocationChecker. Access tokens are
+// revoked one at a time by jti through the revoked_access_tokens table, per
+// login session by sid through revoked_sessions, or all at once by bumping
+// the user's token version. The denylist is kept in
+// memory and refreshed in the background by run, so checking a token never
+// waits on it.
+type tokenRevocations struct {
+	queries *database.Queries
+
+	mu       sync.Mutex
+	denied   map[string]struct{}
+	sessions map[string]struct{}
+	// recent holds jtis and sids revoked on this instance since the last
+	// refresh started, which the refreshed lists may not include yet.
+	recent         map[string]struct{}
+	recentSessions map[string]struct{}
+	versions       map[uuid.UUID]cachedTokenVersion
+}
+
+func newTokenRevocations(q *database.Queries) *tokenRevocations {
+	return &tokenRevocations{
+		queries:        q,
+		denied:         map[string]struct{}{},
+		sessions:       map[string]struct{}{},
+		recent:         map[string]struct{}{},
+		recentSessions: map[string]struct{}{},
+		versions:       map[uuid.UUID]cachedTokenVersion{},
+	}
+}
+
+func (t *tokenRevocations) IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return false, err
+	}
+
+	t.mu.Lock()
+	_, denied := t.denied[claims.ID]
+	if claims.SessionID != "" {
+		if _, ok := t.sessions[claims.SessionID]; ok {
+			denied = true
+		}
+	}
+	cached, ok := t.versions[userID]
+	t.mu.Unlock()
+
+	if denied {
+		return true, nil
+	}
+	if !ok || time.Since(cached.loadedAt) >= revocationTTL {
+		version, err := t.queries.GetUserTokenVersion(ctx, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		cached = t.setVersion(userID, version)
+	}
+	return claims.TokenVersion < cached.version, nil
+}
+
+// run refreshes the denylist every revocationTTL until ctx is done. A failed
+// refresh keeps the previous list and is retried on the next tick.
+func (t *tokenRevocations) run(ctx context.Context) {
+	ticker := time.NewTicker(revocationTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := t.sync(ctx); err != nil {
+			log.Printf("Failed to refresh the access token denylist: %v", err)
+		}
+	}
+}
+
+// sync drops expired rows and reloads the denylist. Expired tokens fail the
+// exp check anyway, so they never need to be remembered. The queries run
+// without the lock; only swapping in the result takes it.
+func (t *tokenRevocations) sync(ctx context.Context) error {
+	t.mu.Lock()
+	t.recent = map[string]struct{}{}
+	t.recentSessions = map[string]struct{}{}
+	t.mu.Unlock()
+
+	if err := t.queries.DeleteExpiredRevokedAccessTokens(ctx); err != nil {
+		return err
+	}
+	if err := t.queries.DeleteExpiredRevokedSessions(ctx); err != nil {
+		return err
+	}
+	jtis, err := t.queries.ListRevokedAccessTokens(ctx)
+	if err != nil {
+		return err
+	}
+	familyIDs, err := t.queries.ListRevokedSessions(ctx)
+	if err != nil {
+		return err
+	}
+	denied := make(map[string]struct{}, len(jtis))
+	for _, jti := range jtis {
+		denied[jti] = struct{}{}
+	}
+	sessions := make(map[string]struct{}, len(familyIDs))
+	for _, id := range familyIDs {
+		sessions[id.String()] = struct{}{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for jti := range t.recent {
+		denied[jti] = struct{}{}
+	}
+	for sid := range t.recentSessions {
+		sessions[sid] = struct{}{}
+	}
+	t.denied = denied
+	t.sessions = sessions
+	for id, v := range t.versions {
+		if time.Since(v.loadedAt) >= revocationTTL {
+			delete(t.versions, id)
+		}
+	}
+	return nil
+}
+
+// revoke denylists a single access token until it expires.
+func (t *tokenRevocations) revoke(ctx context.Context, userID uuid.UUID, claims *auth.Claims) error {
+	err := t.queries.RevokeAccessToken(ctx, database.RevokeAccessTokenParams{
+		Jti:       claims.ID,
+		UserID:    userID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.denied[claims.ID] = struct{}{}
+	t.recent[claims.ID] = struct{}{}
+	return nil
+}
+
+// revokeSessions denies every access token issued from the given login
+// sessions until the longest of them has expired. Revoking the sessions'
+// refresh tokens is up to the caller.
+func (t *tokenRevocations) revokeSessions(ctx context.Context, userID uuid.UUID, familyIDs []uuid.UUID) error {
+	if len(familyIDs) == 0 {
+		return nil
+	}
+	err := t.queries.RevokeSessionAccessTokens(ctx, database.RevokeSessionAccessTokensParams{
+		FamilyIds: familyIDs,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(sessionRevocationTTL),
+	})
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range familyIDs {
+		t.sessions[id.String()] = struct{}{}
+		t.recentSessions[id.String()] = struct{}{}
+	}
+	return nil
+}
+
+// setVersion records a user's current token version, for example right
+// after it was bumped, so this instance stops accepting older tokens at once.
+func (t *tokenRevocations) setVersion(userID uuid.UUID, version int32) cachedTokenVersion {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v := cachedTokenVersion{version: version, loadedAt: time.Now()}
+	t.versions[userID] = v
+	return v
+}
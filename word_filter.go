@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+// wordFilterTTL bounds how long an instance keeps using its cached word list,
+// so edits made through another instance are picked up without a restart.
+const wordFilterTTL = time.Minute
+
+type wordFilterCache struct {
+	mu       sync.Mutex
+	filter   *moderation.Filter
+	loadedAt time.Time
+	// generation is bumped by every invalidation, so a reload that raced
+	// with one does not cache the word list it read before the edit
+	generation uint64
+}
+
+// wordFilter returns the moderation filter built from the moderation_words
+// table, reloading it when the cached copy is stale or was invalidated.
+// The reload runs without holding the lock, so a slow query doesn't stall
+// every other chirp being posted.
+func (cfg *apiConfig) wordFilter(ctx context.Context) (*moderation.Filter, error) {
+	cfg.words.mu.Lock()
+	if cfg.words.filter != nil && time.Since(cfg.words.loadedAt) < wordFilterTTL {
+		defer cfg.words.mu.Unlock()
+		return cfg.words.filter, nil
+	}
+	generation := cfg.words.generation
+	cfg.words.mu.Unlock()
+
+	rows, err := cfg.queries.ListModerationWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]moderation.Rule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, moderation.Rule{Word: row.Word, Action: moderation.Action(row.Action)})
+	}
+	filter := moderation.NewFilter(rules)
+
+	cfg.words.mu.Lock()
+	defer cfg.words.mu.Unlock()
+	if cfg.words.generation == generation {
+		cfg.words.filter = filter
+		cfg.words.loadedAt = time.Now()
+	}
+	return filter, nil
+}
+
+// invalidateWordFilter forces the next wordFilter call to reload.
+func (cfg *apiConfig) invalidateWordFilter() {
+	cfg.words.mu.Lock()
+	defer cfg.words.mu.Unlock()
+	cfg.words.filter = nil
+	cfg.words.generation++
+}
+
+// flagChirp queues a chirp for review once per matched flag word. The
+// reports have no reporter, which marks them as raised by the filter.
+func flagChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID, words []string) error {
+	for _, w := range words {
+		_, err := q.CreateChirpReport(ctx, database.CreateChirpReportParams{
+			ChirpID: chirpID,
+			Reason:  "matched moderation word: " + w,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/SkinnyGilmore1029/Chirpy/internal/database"
+	"github.com/SkinnyGilmore1029/Chirpy/internal/moderation"
+)
+
+func TestWordFilterReload(t *testing.T) {
+	cfg, fake := fakeConfig(t)
+	words := []any{database.ModerationWord{Word: "kerfuffle", Action: string(moderation.ActionMask)}}
+	invalidate := true
+	fake.on("ListModerationWords", func(args []driver.Value) ([]any, error) {
+		// an admin edits the list while this reload is in flight; the lock
+		// is not held during the query, so this must not deadlock
+		if invalidate {
+			invalidate = false
+			cfg.invalidateWordFilter()
+		}
+		return words, nil
+	})
+
+	tests := []struct {
+		name      string
+		wantLoads int
+	}{
+		{"first load races with an edit and is not cached", 1},
+		{"next call reloads", 2},
+		{"cached", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := cfg.wordFilter(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error loading the word filter: %v", err)
+			}
+			if got := filter.Check("what a kerfuffle").Body; got != "what a ****" {
+				t.Errorf("expected the loaded word list to be used, got %q", got)
+			}
+			if got := len(fake.called("ListModerationWords")); got != tt.wantLoads {
+				t.Errorf("expected %d loads, got %d", tt.wantLoads, got)
+			}
+		})
+	}
+}